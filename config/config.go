@@ -4,13 +4,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/internal/service"
 	"github.com/nessibeliyeltay/task-api/pkg/logger"
 )
 
 type Config struct {
-	Server ServerConfig `json:"server"`
-	Logger LoggerConfig `json:"logger"`
+	Server  ServerConfig  `json:"server"`
+	Logger  LoggerConfig  `json:"logger"`
+	Service ServiceConfig `json:"service"`
+	Storage StorageConfig `json:"storage"`
+}
+
+const (
+	StorageBackendMemory = "memory"
+	StorageBackendFile   = "file"
+	StorageBackendSQLite = "sqlite"
+)
+
+// Duration is a time.Duration written as a string such as "5m" in the config file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
 }
 
 type ServerConfig struct {
@@ -40,6 +70,183 @@ func (lc LoggerConfig) ToLoggerConfig() logger.Config {
 	}
 }
 
+type ServiceConfig struct {
+	MaxResultSize int              `json:"max_result_size"`
+	Recovery      RecoveryConfig   `json:"recovery"`
+	Retry         RetryConfig      `json:"retry"`
+	Timeout       TimeoutConfig    `json:"timeout"`
+	Queue         QueueConfig      `json:"queue"`
+	Schedules     ScheduleConfig   `json:"schedules"`
+	Events        EventConfig      `json:"events"`
+	Webhooks      WebhookConfig    `json:"webhooks"`
+	Autoscaler    AutoscalerConfig `json:"autoscaler"`
+	// IdempotencyWindow is how long idempotency keys of created tasks are honoured
+	IdempotencyWindow Duration `json:"idempotency_window"`
+}
+
+type AutoscalerConfig struct {
+	Enabled           bool     `json:"enabled"`
+	MinWorkers        int      `json:"min_workers"`
+	MaxWorkers        int      `json:"max_workers"`
+	Interval          Duration `json:"interval"`
+	ScaleUpQueueDepth int      `json:"scale_up_queue_depth"`
+	ScaleUpLatency    Duration `json:"scale_up_latency"`
+	ScaleUpCooldown   Duration `json:"scale_up_cooldown"`
+	ScaleDownCooldown Duration `json:"scale_down_cooldown"`
+}
+
+type ScheduleConfig struct {
+	MissedRunGrace Duration `json:"missed_run_grace"`
+	MaxCatchUpRuns int      `json:"max_catch_up_runs"`
+}
+
+// WebhookSecretEnv names the environment variable that overrides the
+// webhook secret of the config file
+const WebhookSecretEnv = "TASK_API_WEBHOOK_SECRET"
+
+type WebhookConfig struct {
+	Secret              string        `json:"secret"`
+	Timeout             Duration      `json:"timeout"`
+	MaxAttempts         int           `json:"max_attempts"`
+	Backoff             BackoffConfig `json:"backoff"`
+	Workers             int           `json:"workers"`
+	DeliveryRetention   Duration      `json:"delivery_retention"`
+	MaxDeliveries       int           `json:"max_deliveries"`
+	AllowPrivateTargets bool          `json:"allow_private_targets"`
+}
+
+type EventConfig struct {
+	HistorySize      int `json:"history_size"`
+	SubscriberBuffer int `json:"subscriber_buffer"`
+}
+
+type QueueConfig struct {
+	Capacity         int      `json:"capacity"`
+	AgingInterval    Duration `json:"aging_interval"`
+	FullPolicy       string   `json:"full_policy"`
+	OverflowCapacity int      `json:"overflow_capacity"`
+}
+
+type TimeoutConfig struct {
+	Default Duration `json:"default"`
+	Max     Duration `json:"max"`
+}
+
+type RetryConfig struct {
+	DefaultMaxAttempts int           `json:"default_max_attempts"`
+	MaxAttempts        int           `json:"max_attempts"`
+	Backoff            BackoffConfig `json:"backoff"`
+}
+
+type BackoffConfig struct {
+	Initial    Duration `json:"initial"`
+	Multiplier float64  `json:"multiplier"`
+	Max        Duration `json:"max"`
+	Jitter     float64  `json:"jitter"`
+}
+
+type RecoveryConfig struct {
+	Enabled      bool     `json:"enabled"`
+	StalePolicy  string   `json:"stale_policy"`
+	ReapInterval Duration `json:"reap_interval"`
+	StaleAfter   Duration `json:"stale_after"`
+}
+
+func (sc ServiceConfig) ToServiceConfig() service.Config {
+	return service.Config{
+		MaxResultSize: sc.MaxResultSize,
+		Recovery: service.RecoveryConfig{
+			Enabled:      sc.Recovery.Enabled,
+			StalePolicy:  service.StalePolicy(sc.Recovery.StalePolicy),
+			ReapInterval: time.Duration(sc.Recovery.ReapInterval),
+			StaleAfter:   time.Duration(sc.Recovery.StaleAfter),
+		},
+		Retry: service.RetryConfig{
+			DefaultMaxAttempts: sc.Retry.DefaultMaxAttempts,
+			MaxAttempts:        sc.Retry.MaxAttempts,
+			Backoff: model.BackoffPolicy{
+				Initial:    time.Duration(sc.Retry.Backoff.Initial),
+				Multiplier: sc.Retry.Backoff.Multiplier,
+				Max:        time.Duration(sc.Retry.Backoff.Max),
+				Jitter:     sc.Retry.Backoff.Jitter,
+			},
+		},
+		Timeout: service.TimeoutConfig{
+			Default: time.Duration(sc.Timeout.Default),
+			Max:     time.Duration(sc.Timeout.Max),
+		},
+		Queue: service.QueueConfig{
+			Capacity:         sc.Queue.Capacity,
+			AgingInterval:    time.Duration(sc.Queue.AgingInterval),
+			FullPolicy:       service.QueueFullPolicy(sc.Queue.FullPolicy),
+			OverflowCapacity: sc.Queue.OverflowCapacity,
+		},
+		Schedules: service.ScheduleConfig{
+			MissedRunGrace: time.Duration(sc.Schedules.MissedRunGrace),
+			MaxCatchUpRuns: sc.Schedules.MaxCatchUpRuns,
+		},
+		Events: service.EventConfig{
+			HistorySize:      sc.Events.HistorySize,
+			SubscriberBuffer: sc.Events.SubscriberBuffer,
+		},
+		Webhooks: service.WebhookConfig{
+			Secret:      webhookSecret(sc.Webhooks.Secret),
+			Timeout:     time.Duration(sc.Webhooks.Timeout),
+			MaxAttempts: sc.Webhooks.MaxAttempts,
+			Backoff: model.BackoffPolicy{
+				Initial:    time.Duration(sc.Webhooks.Backoff.Initial),
+				Multiplier: sc.Webhooks.Backoff.Multiplier,
+				Max:        time.Duration(sc.Webhooks.Backoff.Max),
+				Jitter:     sc.Webhooks.Backoff.Jitter,
+			},
+			Workers:             sc.Webhooks.Workers,
+			DeliveryRetention:   time.Duration(sc.Webhooks.DeliveryRetention),
+			MaxDeliveries:       sc.Webhooks.MaxDeliveries,
+			AllowPrivateTargets: sc.Webhooks.AllowPrivateTargets,
+		},
+		Autoscaler: service.AutoscalerConfig{
+			Enabled:           sc.Autoscaler.Enabled,
+			MinWorkers:        sc.Autoscaler.MinWorkers,
+			MaxWorkers:        sc.Autoscaler.MaxWorkers,
+			Interval:          time.Duration(sc.Autoscaler.Interval),
+			ScaleUpQueueDepth: sc.Autoscaler.ScaleUpQueueDepth,
+			ScaleUpLatency:    time.Duration(sc.Autoscaler.ScaleUpLatency),
+			ScaleUpCooldown:   time.Duration(sc.Autoscaler.ScaleUpCooldown),
+			ScaleDownCooldown: time.Duration(sc.Autoscaler.ScaleDownCooldown),
+		},
+		IdempotencyWindow: time.Duration(sc.IdempotencyWindow),
+	}
+}
+
+type StorageConfig struct {
+	Backend         string   `json:"backend"`
+	DataDir         string   `json:"data_dir"`
+	CompactInterval Duration `json:"compact_interval"`
+	SQLitePath      string   `json:"sqlite_path"`
+}
+
+func (sc StorageConfig) ToFileRepositoryConfig() repository.FileRepositoryConfig {
+	return repository.FileRepositoryConfig{
+		Dir:             sc.DataDir,
+		CompactInterval: time.Duration(sc.CompactInterval),
+	}
+}
+
+func (sc StorageConfig) ToSQLiteRepositoryConfig() repository.SQLiteRepositoryConfig {
+	return repository.SQLiteRepositoryConfig{
+		Path: sc.SQLitePath,
+	}
+}
+
+// webhookSecret returns the secret set in the environment, falling back to
+// the one of the config file
+func webhookSecret(configured string) string {
+	if secret := os.Getenv(WebhookSecretEnv); secret != "" {
+		return secret
+	}
+	return configured
+}
+
 func New() *Config {
 	configFile := "config/config.json"
 	data, err := os.ReadFile(configFile)
@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// ProgressEventResponse is the data of a progress event
+type ProgressEventResponse struct {
+	TaskID   string              `json:"task_id"`
+	Status   string              `json:"status"`
+	Progress *model.TaskProgress `json:"progress"`
+}
+
+func NewProgressEventResponse(task *model.Task) *ProgressEventResponse {
+	return &ProgressEventResponse{
+		TaskID:   task.ID,
+		Status:   string(task.Status),
+		Progress: task.Progress,
+	}
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+type CreateScheduleRequest struct {
+	Name string `json:"name"`
+	// Cron is a five field cron expression or a descriptor such as "@hourly"
+	Cron string `json:"cron" binding:"required"`
+	// Timezone is an IANA zone name the expression is evaluated in, UTC by default
+	Timezone string `json:"timezone"`
+	// MissedRunPolicy is one of skip (default), catch_up_once and catch_up_all
+	MissedRunPolicy string             `json:"missed_run_policy"`
+	Task            model.TaskTemplate `json:"task"`
+}
+
+type ScheduleResponse struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Cron            string             `json:"cron"`
+	Timezone        string             `json:"timezone"`
+	Task            model.TaskTemplate `json:"task"`
+	MissedRunPolicy string             `json:"missed_run_policy"`
+	Paused          bool               `json:"paused"`
+	CreatedAt       time.Time          `json:"created_at"`
+	LastRunAt       *time.Time         `json:"last_run_at,omitempty"`
+	LastTaskID      string             `json:"last_task_id,omitempty"`
+	NextRunAt       *time.Time         `json:"next_run_at,omitempty"`
+}
+
+func NewScheduleResponse(schedule *model.Schedule) *ScheduleResponse {
+	return &ScheduleResponse{
+		ID:              schedule.ID,
+		Name:            schedule.Name,
+		Cron:            schedule.Cron,
+		Timezone:        schedule.Timezone,
+		Task:            schedule.Template,
+		MissedRunPolicy: string(schedule.MissedRunPolicy),
+		Paused:          schedule.Paused,
+		CreatedAt:       schedule.CreatedAt,
+		LastRunAt:       schedule.LastRunAt,
+		LastTaskID:      schedule.LastTaskID,
+		NextRunAt:       schedule.NextRunAt,
+	}
+}
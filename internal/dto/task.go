@@ -1,42 +1,164 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/nessibeliyeltay/task-api/internal/model"
 )
 
 type CreateTaskRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description" binding:"required"`
+	Title       string          `json:"title" binding:"required"`
+	Description string          `json:"description" binding:"required"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	MaxAttempts int             `json:"max_attempts"`
+	Backoff     *BackoffPolicy  `json:"backoff"`
+	Timeout     string          `json:"timeout"`
+	Priority    *int            `json:"priority"`
+	// RunAt is an RFC3339 time before which the task must not start
+	RunAt string `json:"run_at"`
+	// Delay postpones the task by a duration such as "10m"
+	Delay string `json:"delay"`
+	// DependsOn lists the IDs of tasks that must complete before this one starts
+	DependsOn []string `json:"depends_on"`
+	// CallbackURL receives a webhook when the task finishes
+	CallbackURL string `json:"callback_url"`
+	// IdempotencyKey comes from the Idempotency-Key header
+	IdempotencyKey string `json:"-"`
+}
+
+// BackoffPolicy overrides the server default retry backoff of a task.
+// Durations are strings such as "5s"; omitted fields keep the default.
+type BackoffPolicy struct {
+	Initial    string  `json:"initial"`
+	Multiplier float64 `json:"multiplier"`
+	Max        string  `json:"max"`
+	Jitter     float64 `json:"jitter"`
+}
+
+// ListTasksRequest selects a page of tasks. Empty filters match every task.
+type ListTasksRequest struct {
+	Statuses []string `form:"-"`
+	Types    []string `form:"-"`
+	// CreatedAfter and CreatedBefore are exclusive RFC3339 bounds of the creation time
+	CreatedAfter  string `form:"created_after"`
+	CreatedBefore string `form:"created_before"`
+	// Sort is created_at (the default) or priority
+	Sort string `form:"sort"`
+	// Order is asc (the default) or desc
+	Order string `form:"order"`
+	// Cursor continues the listing after the page that returned it
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+// EditTaskRequest changes a task that has not started yet. Omitted fields keep
+// their value.
+type EditTaskRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Priority    *int    `json:"priority"`
+	// Payload replaces the payload; null removes it
+	Payload json.RawMessage `json:"payload"`
+	// RunAt and Delay reschedule the task like on creation. An empty value
+	// lets the task run right away.
+	RunAt *string `json:"run_at"`
+	Delay *string `json:"delay"`
+}
+
+type CancelTaskRequest struct {
+	Reason string `json:"reason"`
 }
 
 type TaskResponse struct {
-	ID          string     `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Result      string     `json:"result,omitempty"`
-	Error       string     `json:"error,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	Duration    float64    `json:"duration,omitempty"`
+	ID            string               `json:"id"`
+	Title         string               `json:"title"`
+	Description   string               `json:"description"`
+	Type          string               `json:"type"`
+	Payload       json.RawMessage      `json:"payload,omitempty"`
+	Status        string               `json:"status"`
+	Result        json.RawMessage      `json:"result,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	CancelReason  string               `json:"cancel_reason,omitempty"`
+	CancelledAt   *time.Time           `json:"cancelled_at,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	StartedAt     *time.Time           `json:"started_at,omitempty"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+	Duration      float64              `json:"duration,omitempty"`
+	MaxAttempts   int                  `json:"max_attempts"`
+	Attempts      int                  `json:"attempts"`
+	AttemptLog    []model.AttemptError `json:"attempt_errors,omitempty"`
+	NextRetryAt   *time.Time           `json:"next_retry_at,omitempty"`
+	Timeout       string               `json:"timeout,omitempty"`
+	FailureReason string               `json:"failure_reason,omitempty"`
+	Priority      int                  `json:"priority"`
+	RunAt         *time.Time           `json:"run_at,omitempty"`
+	DependsOn     []string             `json:"depends_on,omitempty"`
+	WorkflowID    string               `json:"workflow_id,omitempty"`
+	Progress      *model.TaskProgress  `json:"progress,omitempty"`
+	CallbackURL   string               `json:"callback_url,omitempty"`
+	History       []model.TaskEdit     `json:"history,omitempty"`
 }
 
 func NewTaskResponse(task *model.Task) *TaskResponse {
 	resp := &TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      string(task.Status),
-		Result:      task.Result,
-		Error:       task.Error,
-		CreatedAt:   task.CreatedAt,
-		StartedAt:   task.StartedAt,
-		CompletedAt: task.CompletedAt,
-		Duration:    task.Duration(),
+		ID:            task.ID,
+		Title:         task.Title,
+		Description:   task.Description,
+		Type:          task.Type,
+		Payload:       task.Payload,
+		Status:        string(task.Status),
+		Result:        task.Result,
+		Error:         task.Error,
+		CancelReason:  task.CancelReason,
+		CancelledAt:   task.CancelledAt,
+		CreatedAt:     task.CreatedAt,
+		StartedAt:     task.StartedAt,
+		CompletedAt:   task.CompletedAt,
+		Duration:      task.Duration(),
+		MaxAttempts:   task.MaxAttempts,
+		Attempts:      task.Attempts,
+		AttemptLog:    task.AttemptLog,
+		NextRetryAt:   task.NextRetryAt,
+		FailureReason: task.FailureReason,
+		Priority:      task.Priority,
+		RunAt:         task.RunAt,
+		DependsOn:     task.DependsOn,
+		WorkflowID:    task.WorkflowID,
+		Progress:      task.Progress,
+		CallbackURL:   task.CallbackURL,
+		History:       task.History,
+	}
+
+	if task.Timeout > 0 {
+		resp.Timeout = task.Timeout.String()
 	}
 
 	return resp
 }
+
+type SearchTasksRequest struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit"`
+}
+
+// SearchResultResponse is a task found by a search and its relevance score
+type SearchResultResponse struct {
+	Score float64       `json:"score"`
+	Task  *TaskResponse `json:"task"`
+}
+
+// BatchItemResponse is the outcome of one item of a batch, by its position in the request
+type BatchItemResponse struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode    string               `json:"mode"`
+	Created int                  `json:"created"`
+	Failed  int                  `json:"failed"`
+	Items   []*BatchItemResponse `json:"items"`
+}
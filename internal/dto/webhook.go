@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Events lists event names such as "task.completed"; all events of
+	// finished tasks by default
+	Events []string `json:"events"`
+}
+
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebhookResponse(webhook *model.WebhookSubscription) *WebhookResponse {
+	return &WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+type DeliveryResponse struct {
+	ID             string                  `json:"id"`
+	TaskID         string                  `json:"task_id"`
+	SubscriptionID string                  `json:"subscription_id,omitempty"`
+	URL            string                  `json:"url"`
+	Event          string                  `json:"event"`
+	Status         string                  `json:"status"`
+	Attempts       []model.DeliveryAttempt `json:"attempts,omitempty"`
+	CreatedAt      time.Time               `json:"created_at"`
+	DeliveredAt    *time.Time              `json:"delivered_at,omitempty"`
+	NextAttemptAt  *time.Time              `json:"next_attempt_at,omitempty"`
+}
+
+func NewDeliveryResponse(delivery *model.WebhookDelivery) *DeliveryResponse {
+	return &DeliveryResponse{
+		ID:             delivery.ID,
+		TaskID:         delivery.TaskID,
+		SubscriptionID: delivery.SubscriptionID,
+		URL:            delivery.URL,
+		Event:          delivery.Event,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		NextAttemptAt:  delivery.NextAttemptAt,
+	}
+}
@@ -0,0 +1,102 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+type ResizeWorkersRequest struct {
+	Count *int `json:"count" binding:"required"`
+}
+
+type WorkerResponse struct {
+	ID             int        `json:"id"`
+	State          string     `json:"state"`
+	TaskID         string     `json:"task_id,omitempty"`
+	TaskStartedAt  *time.Time `json:"task_started_at,omitempty"`
+	TasksProcessed int        `json:"tasks_processed"`
+	StartedAt      time.Time  `json:"started_at"`
+	Retiring       bool       `json:"retiring,omitempty"`
+}
+
+func NewWorkerResponse(worker *model.Worker) *WorkerResponse {
+	return &WorkerResponse{
+		ID:             worker.ID,
+		State:          string(worker.State),
+		TaskID:         worker.TaskID,
+		TaskStartedAt:  worker.TaskStartedAt,
+		TasksProcessed: worker.TasksProcessed,
+		StartedAt:      worker.StartedAt,
+		Retiring:       worker.Retiring,
+	}
+}
+
+// WorkerPoolResponse lists the workers; Count leaves out retiring workers
+type WorkerPoolResponse struct {
+	Count   int               `json:"count"`
+	Workers []*WorkerResponse `json:"workers"`
+}
+
+func NewWorkerPoolResponse(workers []*model.Worker) *WorkerPoolResponse {
+	response := &WorkerPoolResponse{Workers: make([]*WorkerResponse, len(workers))}
+	for i, worker := range workers {
+		response.Workers[i] = NewWorkerResponse(worker)
+		if !worker.Retiring {
+			response.Count++
+		}
+	}
+	return response
+}
+
+type ScalingDecisionResponse struct {
+	At                time.Time `json:"at"`
+	From              int       `json:"from"`
+	To                int       `json:"to"`
+	Reason            string    `json:"reason"`
+	QueueDepth        int       `json:"queue_depth"`
+	OldestWaitSeconds float64   `json:"oldest_wait_seconds"`
+	BusyWorkers       int       `json:"busy_workers"`
+}
+
+type AutoscalerResponse struct {
+	Enabled           bool                       `json:"enabled"`
+	MinWorkers        int                        `json:"min_workers"`
+	MaxWorkers        int                        `json:"max_workers"`
+	Workers           int                        `json:"workers"`
+	BusyWorkers       int                        `json:"busy_workers"`
+	QueueDepth        int                        `json:"queue_depth"`
+	OldestWaitSeconds float64                    `json:"oldest_wait_seconds"`
+	Evaluations       int                        `json:"evaluations"`
+	ScaleUps          int                        `json:"scale_ups"`
+	ScaleDowns        int                        `json:"scale_downs"`
+	Decisions         []*ScalingDecisionResponse `json:"decisions"`
+}
+
+func NewAutoscalerResponse(status *model.AutoscalerStatus) *AutoscalerResponse {
+	response := &AutoscalerResponse{
+		Enabled:           status.Enabled,
+		MinWorkers:        status.MinWorkers,
+		MaxWorkers:        status.MaxWorkers,
+		Workers:           status.Workers,
+		BusyWorkers:       status.BusyWorkers,
+		QueueDepth:        status.QueueDepth,
+		OldestWaitSeconds: status.OldestWait.Seconds(),
+		Evaluations:       status.Evaluations,
+		ScaleUps:          status.ScaleUps,
+		ScaleDowns:        status.ScaleDowns,
+		Decisions:         make([]*ScalingDecisionResponse, len(status.Decisions)),
+	}
+	for i, decision := range status.Decisions {
+		response.Decisions[i] = &ScalingDecisionResponse{
+			At:                decision.At,
+			From:              decision.From,
+			To:                decision.To,
+			Reason:            decision.Reason,
+			QueueDepth:        decision.QueueDepth,
+			OldestWaitSeconds: decision.OldestWait.Seconds(),
+			BusyWorkers:       decision.BusyWorkers,
+		}
+	}
+	return response
+}
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+type CreateWorkflowRequest struct {
+	Tasks []WorkflowTaskRequest `json:"tasks" binding:"required,min=1,dive"`
+}
+
+// WorkflowTaskRequest is a task of a workflow. Its depends_on lists the keys
+// of other tasks in the same workflow rather than task IDs.
+type WorkflowTaskRequest struct {
+	Key string `json:"key" binding:"required"`
+	CreateTaskRequest
+}
+
+type WorkflowResponse struct {
+	ID     string          `json:"id"`
+	Status string          `json:"status"`
+	Counts map[string]int  `json:"counts"`
+	Tasks  []*WorkflowNode `json:"tasks"`
+}
+
+// WorkflowNode is a task in the workflow graph. DependsOn lists the keys of
+// the tasks it waits for, which are the edges of the graph.
+type WorkflowNode struct {
+	Key           string   `json:"key"`
+	TaskID        string   `json:"task_id"`
+	Title         string   `json:"title"`
+	Status        string   `json:"status"`
+	DependsOn     []string `json:"depends_on,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	FailureReason string   `json:"failure_reason,omitempty"`
+}
+
+func NewWorkflowResponse(workflow *model.Workflow) *WorkflowResponse {
+	keys := make(map[string]string, len(workflow.Tasks))
+	for _, task := range workflow.Tasks {
+		keys[task.ID] = task.WorkflowKey
+	}
+
+	resp := &WorkflowResponse{
+		ID:     workflow.ID,
+		Status: string(workflow.Status()),
+		Counts: make(map[string]int),
+		Tasks:  make([]*WorkflowNode, len(workflow.Tasks)),
+	}
+
+	for i, task := range workflow.Tasks {
+		node := &WorkflowNode{
+			Key:           task.WorkflowKey,
+			TaskID:        task.ID,
+			Title:         task.Title,
+			Status:        string(task.Status),
+			Error:         task.Error,
+			FailureReason: task.FailureReason,
+		}
+		for _, id := range task.DependsOn {
+			node.DependsOn = append(node.DependsOn, keys[id])
+		}
+
+		resp.Tasks[i] = node
+		resp.Counts[string(task.Status)]++
+	}
+
+	return resp
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/service"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+type AdminHandler struct {
+	workers    service.WorkerPoolInterface
+	autoscaler service.AutoscalerInterface
+	logger     *logger.Logger
+}
+
+func NewAdminHandler(workers service.WorkerPoolInterface, autoscaler service.AutoscalerInterface, logger *logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		workers:    workers,
+		autoscaler: autoscaler,
+		logger:     logger,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin")
+	{
+		admin.GET("/workers", h.ListWorkers)
+		admin.PUT("/workers", h.ResizeWorkers)
+		admin.GET("/autoscaler", h.GetAutoscaler)
+	}
+}
+
+func (h *AdminHandler) ListWorkers(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.NewWorkerPoolResponse(h.workers.Workers()))
+}
+
+func (h *AdminHandler) ResizeWorkers(c *gin.Context) {
+	var req dto.ResizeWorkersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if h.autoscaler.Enabled() {
+		h.logger.Info("Worker pool resize rejected, autoscaler is enabled", zap.Int("count", *req.Count))
+		c.JSON(http.StatusConflict, gin.H{"error": service.ErrAutoscalerEnabled.Error()})
+		return
+	}
+
+	if err := h.workers.ResizeWorkers(*req.Count); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWorkerCount):
+			h.logger.Info("Invalid worker count", zap.Int("count", *req.Count))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrServiceStopped):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Task service is shutting down"})
+		default:
+			h.logger.Error("Failed to resize worker pool", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resize worker pool"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewWorkerPoolResponse(h.workers.Workers()))
+}
+
+func (h *AdminHandler) GetAutoscaler(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.NewAutoscalerResponse(h.autoscaler.Status()))
+}
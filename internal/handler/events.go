@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/internal/service"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment, so
+// proxies do not close the connection
+const keepAliveInterval = 15 * time.Second
+
+// TaskEvents streams the events of a single task as Server-Sent Events. A new
+// stream starts with the current state of the task, a resumed one with the
+// events missed since Last-Event-ID. The stream ends after the task's result;
+// a finished task whose result is no longer kept ends it with its current state.
+func (h *TaskHandler) TaskEvents(c *gin.Context) {
+	id := c.Param("id")
+
+	lastID, ok := h.lastEventID(c)
+	if !ok {
+		return
+	}
+
+	sub := h.service.SubscribeEvents(service.EventFilter{TaskID: id}, lastID)
+	defer sub.Close()
+
+	task, err := h.service.GetTask(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		case errors.Is(err, repository.ErrTaskNotFound):
+			h.logger.Info("Task not found", zap.String("task_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		default:
+			h.logger.Error("Failed to get task", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get task"})
+		}
+		return
+	}
+
+	startEventStream(c)
+
+	current := service.Event{ID: sub.LastID, Type: service.EventStatus, Time: time.Now(), Task: task}
+	if task.Status.IsTerminal() {
+		current.Type = service.EventResult
+	}
+
+	switch {
+	case lastID == 0:
+		if !h.writeEvent(c, current) || current.Type == service.EventResult {
+			return
+		}
+	case current.Type == service.EventResult && !replaysResult(sub.Replay):
+		// The task has finished but its result has left the history, so no
+		// result would ever end the stream. Close it with the current state.
+		sub.Replay = append(sub.Replay, current)
+	}
+
+	h.streamEvents(c, sub, true)
+}
+
+// Events streams the events of all tasks that pass the filters given in the
+// query: task_id, workflow_id, and comma separated lists of type, status and event
+func (h *TaskHandler) Events(c *gin.Context) {
+	filter := service.EventFilter{
+		TaskID:     c.Query("task_id"),
+		WorkflowID: c.Query("workflow_id"),
+		Types:      queryList(c, "type"),
+	}
+	for _, status := range queryList(c, "status") {
+		filter.Statuses = append(filter.Statuses, model.TaskStatus(status))
+	}
+	for _, event := range queryList(c, "event") {
+		eventType := service.EventType(event)
+		if !eventType.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event type %q", event)})
+			return
+		}
+		filter.Events = append(filter.Events, eventType)
+	}
+
+	lastID, ok := h.lastEventID(c)
+	if !ok {
+		return
+	}
+
+	sub := h.service.SubscribeEvents(filter, lastID)
+	defer sub.Close()
+
+	startEventStream(c)
+	h.streamEvents(c, sub, false)
+}
+
+// streamEvents writes the replayed and then the new events of the subscription
+// until the client goes away or the subscription is closed. A task stream
+// also ends after the task's result.
+func (h *TaskHandler) streamEvents(c *gin.Context, sub *service.Subscription, untilResult bool) {
+	for _, event := range sub.Replay {
+		if !h.writeEvent(c, event) || (untilResult && event.Type == service.EventResult) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !h.writeEvent(c, event) || (untilResult && event.Type == service.EventResult) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// replaysResult reports whether the replayed events include a result
+func replaysResult(events []service.Event) bool {
+	for _, event := range events {
+		if event.Type == service.EventResult {
+			return true
+		}
+	}
+	return false
+}
+
+// lastEventID reads the event to resume after from the Last-Event-ID header,
+// or the last_event_id query parameter for clients that can not set headers
+func (h *TaskHandler) lastEventID(c *gin.Context) (uint64, bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, true
+	}
+
+	lastID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		h.logger.Info("Invalid last event ID", zap.String("last_event_id", value))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last event ID"})
+		return 0, false
+	}
+	return lastID, true
+}
+
+func startEventStream(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// writeEvent sends one event to the client. It returns false if the stream
+// should end.
+func (h *TaskHandler) writeEvent(c *gin.Context, event service.Event) bool {
+	var payload any = dto.NewTaskResponse(event.Task)
+	if event.Type == service.EventProgress {
+		payload = dto.NewProgressEventResponse(event.Task)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		h.logger.Error("Failed to encode event", err, zap.Uint64("event_id", event.ID))
+		return false
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// queryList splits a comma separated query parameter
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/internal/service"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+type ScheduleHandler struct {
+	service service.ScheduleServiceInterface
+	logger  *logger.Logger
+}
+
+func NewScheduleHandler(service service.ScheduleServiceInterface, logger *logger.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *ScheduleHandler) RegisterRoutes(router *gin.Engine) {
+	schedules := router.Group("/api/v1/schedules")
+	{
+		schedules.POST("", h.CreateSchedule)
+		schedules.GET("", h.ListSchedules)
+		schedules.GET("/:id", h.GetSchedule)
+		schedules.DELETE("/:id", h.DeleteSchedule)
+		schedules.POST("/:id/pause", h.PauseSchedule)
+		schedules.POST("/:id/resume", h.ResumeSchedule)
+	}
+}
+
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req dto.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCronExpression), errors.Is(err, service.ErrInvalidTimezone),
+			errors.Is(err, service.ErrInvalidMissedRunPolicy), errors.Is(err, service.ErrInvalidTaskTemplate):
+			h.logger.Info("Invalid schedule request", zap.String("cron", req.Cron), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to create schedule", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewScheduleResponse(schedule))
+}
+
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.service.ListSchedules()
+	if err != nil {
+		h.logger.Error("Failed to list schedules", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schedules"})
+		return
+	}
+
+	response := make([]*dto.ScheduleResponse, len(schedules))
+	for i, schedule := range schedules {
+		response[i] = dto.NewScheduleResponse(schedule)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	id := c.Param("id")
+
+	schedule, err := h.service.GetSchedule(id)
+	if err != nil {
+		h.handleScheduleError(c, id, "get", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewScheduleResponse(schedule))
+}
+
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteSchedule(id); err != nil {
+		h.handleScheduleError(c, id, "delete", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ScheduleHandler) PauseSchedule(c *gin.Context) {
+	h.setPaused(c, h.service.PauseSchedule, "pause")
+}
+
+func (h *ScheduleHandler) ResumeSchedule(c *gin.Context) {
+	h.setPaused(c, h.service.ResumeSchedule, "resume")
+}
+
+func (h *ScheduleHandler) setPaused(c *gin.Context, action func(id string) (*model.Schedule, error), name string) {
+	id := c.Param("id")
+
+	schedule, err := action(id)
+	if err != nil {
+		h.handleScheduleError(c, id, name, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewScheduleResponse(schedule))
+}
+
+// handleScheduleError writes the response for an error returned by an
+// operation on a single schedule
+func (h *ScheduleHandler) handleScheduleError(c *gin.Context, id, operation string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidScheduleID):
+		h.logger.Info("Invalid schedule ID format", zap.String("schedule_id", id))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID format"})
+	case errors.Is(err, repository.ErrScheduleNotFound):
+		h.logger.Info("Schedule not found", zap.String("schedule_id", id))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+	default:
+		h.logger.Error("Failed to "+operation+" schedule", err, zap.String("schedule_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + operation + " schedule"})
+	}
+}
@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -30,44 +36,257 @@ func (h *TaskHandler) RegisterRoutes(router *gin.Engine) {
 	{
 		tasks.POST("", h.CreateTask)
 		tasks.GET("", h.ListTasks)
+		tasks.GET("/search", h.SearchTasks)
 		tasks.GET("/:id", h.GetTask)
+		tasks.PATCH("/:id", h.EditTask)
 		tasks.DELETE("/:id", h.DeleteTask)
+		tasks.POST("/:id/cancel", h.CancelTask)
+		tasks.GET("/:id/events", h.TaskEvents)
+		tasks.GET("/:id/wait", h.WaitTask)
+	}
+
+	// Gin reads ":batch" as a path parameter, so the action is checked in the handler
+	router.POST("/api/v1/tasks:action", h.TaskAction)
+
+	router.GET("/api/v1/events", h.Events)
+
+	deadLetter := router.Group("/api/v1/dead-letter")
+	{
+		deadLetter.GET("", h.ListDeadLetterTasks)
+		deadLetter.POST("/:id/redrive", h.RedriveTask)
+	}
+
+	workflows := router.Group("/api/v1/workflows")
+	{
+		workflows.POST("", h.CreateWorkflow)
+		workflows.GET("/:id", h.GetWorkflow)
 	}
 }
 
+// idempotencyKeyHeader lets clients retry task creation without creating duplicates
+const idempotencyKeyHeader = "Idempotency-Key"
+
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var req dto.CreateTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	req.IdempotencyKey = c.GetHeader(idempotencyKeyHeader)
 
 	task, err := h.service.CreateTask(c.Request.Context(), req)
 	if err != nil {
-		h.logger.Error("Failed to create task", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		switch {
+		case errors.Is(err, service.ErrUnknownTaskType):
+			h.logger.Info("Unknown task type", zap.String("type", req.Type))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown task type"})
+		case isInvalidTaskRequest(err), errors.Is(err, service.ErrInvalidIdempotencyKey):
+			h.logger.Info("Invalid task request", zap.String("type", req.Type), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			h.logger.Info("Idempotency key reused", zap.String("idempotency_key", req.IdempotencyKey))
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency key was already used for a different request"})
+		case errors.Is(err, service.ErrQueueFull):
+			h.queueFull(c)
+		default:
+			h.logger.Error("Failed to create task", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusCreated, dto.NewTaskResponse(task))
 }
 
+// TaskAction serves the custom methods of the task collection, such as POST /api/v1/tasks:batch
+func (h *TaskHandler) TaskAction(c *gin.Context) {
+	switch c.Param("action") {
+	case ":batch":
+		h.CreateTaskBatch(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	}
+}
+
+func (h *TaskHandler) CreateTaskBatch(c *gin.Context) {
+	// Items are validated one by one by the service, so a single bad item
+	// does not fail the whole request in best-effort mode
+	var reqs []dto.CreateTaskRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	mode := service.BatchMode(c.Query("mode"))
+	result, err := h.service.CreateTaskBatch(c.Request.Context(), mode, reqs)
+	if err != nil && !errors.Is(err, service.ErrBatchRejected) {
+		switch {
+		case errors.Is(err, service.ErrInvalidBatch):
+			h.logger.Info("Invalid task batch", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrQueueFull):
+			h.queueFull(c)
+		default:
+			h.logger.Error("Failed to create task batch", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task batch"})
+		}
+		return
+	}
+
+	response := &dto.BatchResponse{
+		Mode:    string(result.Mode),
+		Created: result.Created,
+		Failed:  result.Failed,
+		Items:   make([]*dto.BatchItemResponse, len(result.Items)),
+	}
+	for i, item := range result.Items {
+		response.Items[i] = &dto.BatchItemResponse{Index: i}
+		switch {
+		case item.Task != nil:
+			response.Items[i].ID = item.Task.ID
+		case item.Err != nil:
+			response.Items[i].Error = batchItemError(item.Err)
+			if errors.Is(item.Err, service.ErrQueueFull) {
+				setRetryAfter(c, h.service.RetryAfter())
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		h.logger.Info("Task batch rejected", zap.Int("failed", result.Failed))
+		c.JSON(http.StatusUnprocessableEntity, response)
+	case result.Failed > 0:
+		c.JSON(http.StatusMultiStatus, response)
+	default:
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// batchItemError describes why an item of a batch failed, without exposing
+// internal errors
+func batchItemError(err error) string {
+	if isInvalidTaskRequest(err) || errors.Is(err, service.ErrUnknownTaskType) ||
+		errors.Is(err, service.ErrMissingTaskField) || errors.Is(err, service.ErrQueueFull) {
+		return err.Error()
+	}
+	return "Failed to create task"
+}
+
+// queueFull tells the client that no task is accepted until the queue drains
+// and when to try again
+func (h *TaskHandler) queueFull(c *gin.Context) {
+	retryAfter := h.service.RetryAfter()
+	h.logger.Info("Task rejected, queue is full", zap.Duration("retry_after", retryAfter))
+	setRetryAfter(c, retryAfter)
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Task queue is full, retry later"})
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(max(seconds, 1), 10))
+}
+
+// isInvalidTaskRequest reports whether the service rejected a task because of
+// a problem with the request
+func isInvalidTaskRequest(err error) bool {
+	for _, target := range []error{
+		service.ErrInvalidPayload,
+		service.ErrInvalidRetryPolicy,
+		service.ErrInvalidTimeout,
+		service.ErrInvalidPriority,
+		service.ErrInvalidSchedule,
+		service.ErrDependencyNotFound,
+		service.ErrInvalidCallbackURL,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *TaskHandler) ListTasks(c *gin.Context) {
-	tasks, err := h.service.ListTasks()
+	var req dto.ListTasksRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Info("Invalid list query", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Statuses = queryList(c, "status")
+	req.Types = queryList(c, "type")
+
+	page, err := h.service.ListTasks(req)
 	if err != nil {
-		h.logger.Error("Failed to list tasks", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tasks"})
+		switch {
+		case errors.Is(err, service.ErrInvalidListQuery):
+			h.logger.Info("Invalid list query", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to list tasks", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tasks"})
+		}
 		return
 	}
 
-	response := make([]*dto.TaskResponse, len(tasks))
-	for i, task := range tasks {
+	// The body stays a plain array; the next page is announced in headers
+	if page.NextCursor != "" {
+		c.Header(nextCursorHeader, page.NextCursor)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, nextPageURL(c.Request.URL, page.NextCursor)))
+	}
+
+	response := make([]*dto.TaskResponse, len(page.Tasks))
+	for i, task := range page.Tasks {
 		response[i] = dto.NewTaskResponse(task)
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// nextCursorHeader carries the cursor of the next page of a task listing
+const nextCursorHeader = "X-Next-Cursor"
+
+// nextPageURL returns the request URL with its cursor moved on to cursor
+func nextPageURL(current *url.URL, cursor string) string {
+	query := current.Query()
+	query.Set("cursor", cursor)
+
+	next := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	return next.String()
+}
+
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	var req dto.SearchTasksRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Info("Invalid search query", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.service.SearchTasks(req.Query, req.Limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidSearchQuery):
+			h.logger.Info("Invalid search query", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSearchUnavailable):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Task search is not available"})
+		default:
+			h.logger.Error("Failed to search tasks", err, zap.String("query", req.Query))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search tasks"})
+		}
+		return
+	}
+
+	response := make([]*dto.SearchResultResponse, len(results))
+	for i, result := range results {
+		response[i] = &dto.SearchResultResponse{Score: result.Score, Task: dto.NewTaskResponse(result.Task)}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -95,6 +314,51 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.NewTaskResponse(task))
 }
 
+// WaitTask blocks until the task finishes or the timeout given in the query
+// expires. A task that is still running when it expires is returned with
+// 202 Accepted instead of 200 OK.
+func (h *TaskHandler) WaitTask(c *gin.Context) {
+	id := c.Param("id")
+
+	timeout := service.DefaultWaitTimeout
+	if value := c.Query("timeout"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			h.logger.Info("Invalid wait timeout", zap.String("timeout", value))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wait timeout"})
+			return
+		}
+		timeout = parsed
+	}
+
+	task, err := h.service.WaitTask(c.Request.Context(), id, timeout)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWaitTimedOut):
+			c.JSON(http.StatusAccepted, dto.NewTaskResponse(task))
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		case errors.Is(err, service.ErrInvalidWaitTimeout):
+			h.logger.Info("Invalid wait timeout", zap.Duration("timeout", timeout))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrTaskNotFound):
+			h.logger.Info("Task not found", zap.String("task_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrServiceStopped):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Task service is shutting down"})
+		case errors.Is(err, context.Canceled):
+			// The client went away, there is no one to answer
+		default:
+			h.logger.Error("Failed to wait for task", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to wait for task"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewTaskResponse(task))
+}
+
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -120,3 +384,178 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func (h *TaskHandler) EditTask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.EditTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	task, err := h.service.EditTask(id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		case errors.Is(err, repository.ErrTaskNotFound):
+			h.logger.Info("Task not found", zap.String("task_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrTaskNotEditable):
+			h.logger.Info("Task cannot be edited", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task has already started"})
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.logger.Info("Task changed concurrently", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task was changed concurrently, retry the request"})
+		case errors.Is(err, service.ErrInvalidTaskUpdate), isInvalidTaskRequest(err):
+			h.logger.Info("Invalid task update", zap.String("task_id", id), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to edit task", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit task"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewTaskResponse(task))
+}
+
+func (h *TaskHandler) CancelTask(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		h.logger.Info("Invalid request: missing task ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task ID is required"})
+		return
+	}
+
+	// The body is optional, a cancel request without one gets the default reason
+	var req dto.CancelTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	task, err := h.service.CancelTask(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		case errors.Is(err, repository.ErrTaskNotFound):
+			h.logger.Info("Task not found", zap.String("task_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrTaskNotCancellable):
+			h.logger.Info("Task cannot be cancelled", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task has already finished"})
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.logger.Info("Task changed concurrently", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task was changed concurrently, retry the request"})
+		default:
+			h.logger.Error("Failed to cancel task", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel task"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewTaskResponse(task))
+}
+
+func (h *TaskHandler) ListDeadLetterTasks(c *gin.Context) {
+	tasks, err := h.service.ListDeadLetterTasks()
+	if err != nil {
+		h.logger.Error("Failed to list dead letter tasks", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list dead letter tasks"})
+		return
+	}
+
+	response := make([]*dto.TaskResponse, len(tasks))
+	for i, task := range tasks {
+		response[i] = dto.NewTaskResponse(task)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TaskHandler) RedriveTask(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		h.logger.Info("Invalid request: missing task ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task ID is required"})
+		return
+	}
+
+	task, err := h.service.RedriveTask(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		case errors.Is(err, repository.ErrTaskNotFound):
+			h.logger.Info("Task not found", zap.String("task_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		case errors.Is(err, service.ErrTaskNotDeadLettered):
+			h.logger.Info("Task is not in the dead letter queue", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task is not in the dead letter queue"})
+		case errors.Is(err, repository.ErrVersionConflict):
+			h.logger.Info("Task changed concurrently", zap.String("task_id", id))
+			c.JSON(http.StatusConflict, gin.H{"error": "Task was changed concurrently, retry the request"})
+		case errors.Is(err, service.ErrQueueFull):
+			h.queueFull(c)
+		default:
+			h.logger.Error("Failed to redrive task", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redrive task"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewTaskResponse(task))
+}
+
+func (h *TaskHandler) CreateWorkflow(c *gin.Context) {
+	var req dto.CreateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	workflow, err := h.service.CreateWorkflow(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWorkflow), errors.Is(err, service.ErrDependencyCycle),
+			errors.Is(err, service.ErrUnknownTaskType), isInvalidTaskRequest(err):
+			h.logger.Info("Invalid workflow request", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrQueueFull):
+			h.queueFull(c)
+		default:
+			h.logger.Error("Failed to create workflow", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewWorkflowResponse(workflow))
+}
+
+func (h *TaskHandler) GetWorkflow(c *gin.Context) {
+	id := c.Param("id")
+
+	workflow, err := h.service.GetWorkflow(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrWorkflowNotFound):
+			h.logger.Info("Workflow not found", zap.String("workflow_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		default:
+			h.logger.Error("Failed to get workflow", err, zap.String("workflow_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workflow"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewWorkflowResponse(workflow))
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/service"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+// fakeTaskService отвечает на запросы заранее заданным результатом
+type fakeTaskService struct {
+	service.TaskServiceInterface
+	mode   service.BatchMode
+	reqs   []dto.CreateTaskRequest
+	result *service.BatchResult
+	page   *service.TaskPage
+	task   *model.Task
+	events *service.EventBus
+	err    error
+}
+
+func (s *fakeTaskService) GetTask(_ string) (*model.Task, error) {
+	return s.task, s.err
+}
+
+func (s *fakeTaskService) SubscribeEvents(filter service.EventFilter, lastID uint64) *service.Subscription {
+	return s.events.Subscribe(filter, lastID)
+}
+
+func (s *fakeTaskService) ListTasks(_ dto.ListTasksRequest) (*service.TaskPage, error) {
+	return s.page, s.err
+}
+
+func (s *fakeTaskService) CreateTaskBatch(_ context.Context, mode service.BatchMode, reqs []dto.CreateTaskRequest) (*service.BatchResult, error) {
+	s.mode = mode
+	s.reqs = reqs
+	return s.result, s.err
+}
+
+func (s *fakeTaskService) RedriveTask(_ context.Context, _ string) (*model.Task, error) {
+	return nil, s.err
+}
+
+func (s *fakeTaskService) WaitTask(_ context.Context, _ string, _ time.Duration) (*model.Task, error) {
+	return nil, s.err
+}
+
+func (s *fakeTaskService) RetryAfter() time.Duration {
+	return 2500 * time.Millisecond
+}
+
+func newTestRouter(svc service.TaskServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	config := logger.DefaultConfig()
+	config.LogToFile = false
+	config.LogToStdout = true
+
+	router := gin.New()
+	NewTaskHandler(svc, logger.New(config)).RegisterRoutes(router)
+	return router
+}
+
+func postBatch(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	body := `[{"title":"First","description":"Description"},{"title":"Second","description":"Description"}]`
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBatch(t *testing.T, rec *httptest.ResponseRecorder) dto.BatchResponse {
+	t.Helper()
+
+	var response dto.BatchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response
+}
+
+func TestCreateTaskBatchHandler(t *testing.T) {
+	created := func(id string) service.BatchItemResult {
+		return service.BatchItemResult{Task: &model.Task{ID: id}}
+	}
+
+	t.Run("created", func(t *testing.T) {
+		svc := &fakeTaskService{result: &service.BatchResult{
+			Mode:    service.BatchAllOrNothing,
+			Items:   []service.BatchItemResult{created("1"), created("2")},
+			Created: 2,
+		}}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch")
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Empty(t, svc.mode)
+		assert.Len(t, svc.reqs, 2)
+
+		response := decodeBatch(t, rec)
+		assert.Equal(t, "all_or_nothing", response.Mode)
+		assert.Equal(t, 2, response.Created)
+		assert.Equal(t, "1", response.Items[0].ID)
+		assert.Equal(t, "2", response.Items[1].ID)
+		assert.Equal(t, 1, response.Items[1].Index)
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		svc := &fakeTaskService{result: &service.BatchResult{
+			Mode:    service.BatchBestEffort,
+			Items:   []service.BatchItemResult{created("1"), {Err: service.ErrQueueFull}},
+			Created: 1,
+			Failed:  1,
+		}}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch?mode=best_effort")
+
+		assert.Equal(t, http.StatusMultiStatus, rec.Code)
+		assert.Equal(t, service.BatchBestEffort, svc.mode)
+		// Отклонённый из-за полной очереди элемент подсказывает, когда повторить запрос
+		assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+
+		response := decodeBatch(t, rec)
+		assert.Equal(t, "1", response.Items[0].ID)
+		assert.Empty(t, response.Items[1].ID)
+		assert.Equal(t, service.ErrQueueFull.Error(), response.Items[1].Error)
+	})
+
+	t.Run("internal item error", func(t *testing.T) {
+		svc := &fakeTaskService{result: &service.BatchResult{
+			Mode:    service.BatchBestEffort,
+			Items:   []service.BatchItemResult{{Err: errors.New("disk full")}, created("1")},
+			Created: 1,
+			Failed:  1,
+		}}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch?mode=best_effort")
+
+		assert.Equal(t, http.StatusMultiStatus, rec.Code)
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+		// Внутренние ошибки не раскрываются клиенту
+		assert.Equal(t, "Failed to create task", decodeBatch(t, rec).Items[0].Error)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		svc := &fakeTaskService{
+			result: &service.BatchResult{
+				Mode:   service.BatchAllOrNothing,
+				Items:  []service.BatchItemResult{{}, {Err: service.ErrMissingTaskField}},
+				Failed: 1,
+			},
+			err: service.ErrBatchRejected,
+		}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		response := decodeBatch(t, rec)
+		assert.Zero(t, response.Created)
+		assert.Empty(t, response.Items[0].ID)
+		assert.Empty(t, response.Items[0].Error)
+		assert.Equal(t, service.ErrMissingTaskField.Error(), response.Items[1].Error)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		svc := &fakeTaskService{err: service.ErrQueueFull}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch")
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		svc := &fakeTaskService{err: errors.Wrap(service.ErrInvalidBatch, `unknown mode "partial"`)}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch?mode=partial")
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		svc := &fakeTaskService{err: errors.New("disk full")}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:batch")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		svc := &fakeTaskService{}
+		rec := postBatch(newTestRouter(svc), "/api/v1/tasks:merge")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Nil(t, svc.reqs)
+	})
+}
+
+func TestRedriveTaskHandler(t *testing.T) {
+	svc := &fakeTaskService{err: service.ErrQueueFull}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/dead-letter/1/redrive", nil)
+	rec := httptest.NewRecorder()
+	newTestRouter(svc).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+}
+
+func TestWaitTaskHandler(t *testing.T) {
+	svc := &fakeTaskService{err: service.ErrServiceStopped}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1/wait", nil)
+	rec := httptest.NewRecorder()
+	newTestRouter(svc).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestTaskEventsHandler(t *testing.T) {
+	events := service.NewEventBus(service.EventConfig{HistorySize: 2, SubscriberBuffer: 10})
+	defer events.Close()
+
+	task := &model.Task{ID: "1", Status: model.StatusCompleted}
+	events.Publish(service.EventStatus, task)
+	events.Publish(service.EventResult, task)
+	// Результат задачи вытесняется из истории событиями других задач
+	events.Publish(service.EventStatus, &model.Task{ID: "2"})
+	events.Publish(service.EventStatus, &model.Task{ID: "2"})
+
+	svc := &fakeTaskService{task: task, events: events}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+
+	// Поток завершённой задачи закрывается, а не ждёт результата, которого не будет
+	done := make(chan struct{})
+	go func() {
+		newTestRouter(svc).ServeHTTP(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("event stream did not end")
+	}
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "id: 4\nevent: result\n"), rec.Body.String())
+}
+
+func TestListTasksHandler(t *testing.T) {
+	list := func(svc *fakeTaskService) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks?status=completed&limit=1", nil)
+		rec := httptest.NewRecorder()
+		newTestRouter(svc).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("next page", func(t *testing.T) {
+		rec := list(&fakeTaskService{page: &service.TaskPage{Tasks: []*model.Task{{ID: "1"}}, NextCursor: "abc"}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Тело остаётся массивом, курсор следующей страницы передаётся в заголовках
+		var tasks []dto.TaskResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tasks))
+		require.Len(t, tasks, 1)
+		assert.Equal(t, "1", tasks[0].ID)
+		assert.Equal(t, "abc", rec.Header().Get("X-Next-Cursor"))
+		assert.Equal(t, `</api/v1/tasks?cursor=abc&limit=1&status=completed>; rel="next"`, rec.Header().Get("Link"))
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		rec := list(&fakeTaskService{page: &service.TaskPage{Tasks: []*model.Task{}}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[]`, rec.Body.String())
+		assert.Empty(t, rec.Header().Get("X-Next-Cursor"))
+		assert.Empty(t, rec.Header().Get("Link"))
+	})
+}
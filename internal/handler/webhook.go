@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/internal/service"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+type WebhookHandler struct {
+	service service.WebhookServiceInterface
+	logger  *logger.Logger
+}
+
+func NewWebhookHandler(service service.WebhookServiceInterface, logger *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router *gin.Engine) {
+	webhooks := router.Group("/api/v1/webhooks")
+	{
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+	}
+
+	router.GET("/api/v1/tasks/:id/deliveries", h.ListDeliveries)
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebhookURL), errors.Is(err, service.ErrInvalidWebhookEvent):
+			h.logger.Info("Invalid webhook request", zap.String("url", req.URL), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to create webhook", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.NewWebhookResponse(webhook))
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.ListWebhooks()
+	if err != nil {
+		h.logger.Error("Failed to list webhooks", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+		return
+	}
+
+	response := make([]*dto.WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		response[i] = dto.NewWebhookResponse(webhook)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteWebhook(id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebhookID):
+			h.logger.Info("Invalid webhook ID format", zap.String("webhook_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID format"})
+		case errors.Is(err, repository.ErrWebhookNotFound):
+			h.logger.Info("Webhook not found", zap.String("webhook_id", id))
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		default:
+			h.logger.Error("Failed to delete webhook", err, zap.String("webhook_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id := c.Param("id")
+
+	deliveries, err := h.service.ListDeliveries(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTaskID):
+			h.logger.Info("Invalid task ID format", zap.String("task_id", id))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
+		default:
+			h.logger.Error("Failed to list webhook deliveries", err, zap.String("task_id", id))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook deliveries"})
+		}
+		return
+	}
+
+	response := make([]*dto.DeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = dto.NewDeliveryResponse(delivery)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
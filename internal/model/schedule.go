@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MissedRunPolicy decides what a schedule does about runs that were due while
+// the service was down
+type MissedRunPolicy string
+
+const (
+	// MissedRunSkip drops missed runs and waits for the next one
+	MissedRunSkip MissedRunPolicy = "skip"
+	// MissedRunCatchUpOnce starts a single task for any number of missed runs
+	MissedRunCatchUpOnce MissedRunPolicy = "catch_up_once"
+	// MissedRunCatchUpAll starts a task for every missed run
+	MissedRunCatchUpAll MissedRunPolicy = "catch_up_all"
+)
+
+// TaskTemplate describes the task a schedule creates each time it fires
+type TaskTemplate struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Type        string          `json:"type,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	MaxAttempts int             `json:"max_attempts,omitempty"`
+	Timeout     string          `json:"timeout,omitempty"`
+	Priority    *int            `json:"priority,omitempty"`
+}
+
+// Schedule creates a task from its template every time its cron expression fires
+type Schedule struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Cron            string          `json:"cron"`
+	Timezone        string          `json:"timezone"`
+	Template        TaskTemplate    `json:"task"`
+	MissedRunPolicy MissedRunPolicy `json:"missed_run_policy"`
+	Paused          bool            `json:"paused"`
+	CreatedAt       time.Time       `json:"created_at"`
+	LastRunAt       *time.Time      `json:"last_run_at,omitempty"`
+	LastTaskID      string          `json:"last_task_id,omitempty"`
+	NextRunAt       *time.Time      `json:"next_run_at,omitempty"`
+}
+
+// Clone returns a deep copy of the schedule
+func (s *Schedule) Clone() *Schedule {
+	if s == nil {
+		return nil
+	}
+
+	clone := *s
+	clone.Template.Payload = cloneRaw(s.Template.Payload)
+	if s.Template.Priority != nil {
+		priority := *s.Template.Priority
+		clone.Template.Priority = &priority
+	}
+	clone.LastRunAt = cloneTime(s.LastRunAt)
+	clone.NextRunAt = cloneTime(s.NextRunAt)
+	return &clone
+}
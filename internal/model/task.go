@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"slices"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -9,34 +11,143 @@ import (
 var taskCounter uint64
 
 type Task struct {
-	ID          string     `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      TaskStatus `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
-	DurationStr string     `json:"duration,omitempty"`
-	Result      string     `json:"result,omitempty"`
-	Error       string     `json:"error,omitempty"`
+	ID            string          `json:"id"`
+	Title         string          `json:"title"`
+	Description   string          `json:"description"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Status        TaskStatus      `json:"status"`
+	CreatedAt     time.Time       `json:"created_at"`
+	StartedAt     *time.Time      `json:"started_at,omitempty"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+	DurationStr   string          `json:"duration,omitempty"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	CancelledAt   *time.Time      `json:"cancelled_at,omitempty"`
+	CancelReason  string          `json:"cancel_reason,omitempty"`
+	MaxAttempts   int             `json:"max_attempts"`
+	Attempts      int             `json:"attempts"`
+	Backoff       BackoffPolicy   `json:"backoff"`
+	AttemptLog    []AttemptError  `json:"attempt_errors,omitempty"`
+	NextRetryAt   *time.Time      `json:"next_retry_at,omitempty"`
+	Timeout       time.Duration   `json:"timeout"`
+	FailureReason string          `json:"failure_reason,omitempty"`
+	Priority      int             `json:"priority"`
+	RunAt         *time.Time      `json:"run_at,omitempty"`
+	DependsOn     []string        `json:"depends_on,omitempty"`
+	WorkflowID    string          `json:"workflow_id,omitempty"`
+	WorkflowKey   string          `json:"workflow_key,omitempty"`
+	Progress      *TaskProgress   `json:"progress,omitempty"`
+	CallbackURL   string          `json:"callback_url,omitempty"`
+	History       []TaskEdit      `json:"history,omitempty"`
+	// IdempotencyKey is the key the client created the task with, and
+	// RequestHash fingerprints that request
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	RequestHash    string `json:"request_hash,omitempty"`
+	// Version is advanced by the repository on every update, so an update
+	// based on an outdated copy of the task can be refused
+	Version int64 `json:"version"`
+}
+
+// Task priorities, higher values run first
+const (
+	MinPriority     = 0
+	DefaultPriority = 5
+	MaxPriority     = 10
+)
+
+// Failure reasons of tasks that did not fail in their executor.
+// Other failures leave the reason empty and only carry the error message.
+const (
+	// FailureReasonTimedOut is set on a task that exceeded its timeout
+	FailureReasonTimedOut = "timed_out"
+	// FailureReasonDependencyFailed is set on a task whose dependency did not complete
+	FailureReasonDependencyFailed = "dependency_failed"
+)
+
+// BackoffPolicy controls the delay between attempts of a failing task.
+// The delay after attempt n is Initial*Multiplier^(n-1), capped at Max, and
+// randomly spread by up to Jitter (a fraction of the delay) in either direction.
+type BackoffPolicy struct {
+	Initial    time.Duration `json:"initial"`
+	Multiplier float64       `json:"multiplier"`
+	Max        time.Duration `json:"max"`
+	Jitter     float64       `json:"jitter"`
+}
+
+// TaskProgress is the latest progress reported by the executor of a task
+type TaskProgress struct {
+	Percent   float64   `json:"percent"`
+	Stage     string    `json:"stage,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ETA is the estimated completion time of a running task
+	ETA *time.Time `json:"eta,omitempty"`
+}
+
+// AttemptError records why a single attempt of a task failed
+type AttemptError struct {
+	Attempt  int       `json:"attempt"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// TaskEdit records the fields changed by one edit of a task
+type TaskEdit struct {
+	EditedAt time.Time     `json:"edited_at"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// FieldChange holds the JSON values of a task field before and after an edit
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
 }
 
 type TaskStatus string
 
 const (
+	StatusScheduled  TaskStatus = "scheduled"
+	StatusBlocked    TaskStatus = "blocked"
 	StatusPending    TaskStatus = "pending"
 	StatusProcessing TaskStatus = "processing"
 	StatusCompleted  TaskStatus = "completed"
 	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
+	StatusDeadLetter TaskStatus = "dead_letter"
 )
 
-func NewTask(title, description string) *Task {
+// IsTerminal reports whether a task in this status will not change any more
+func (s TaskStatus) IsTerminal() bool {
+	switch s {
+	case StatusCompleted, StatusFailed, StatusCancelled, StatusDeadLetter:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValid reports whether s is one of the known task statuses
+func (s TaskStatus) IsValid() bool {
+	switch s {
+	case StatusScheduled, StatusBlocked, StatusPending, StatusProcessing:
+		return true
+	default:
+		return s.IsTerminal()
+	}
+}
+
+func NewTask(title, description, taskType string, payload json.RawMessage) *Task {
 	return &Task{
 		ID:          generateTaskID(),
 		Title:       title,
 		Description: description,
+		Type:        taskType,
+		Payload:     payload,
 		Status:      StatusPending,
 		CreatedAt:   time.Now(),
+		Priority:    DefaultPriority,
 	}
 }
 
@@ -65,7 +176,7 @@ func (t *Task) UpdateStatus(status TaskStatus) {
 	switch status {
 	case StatusProcessing:
 		t.StartedAt = &now
-	case StatusCompleted, StatusFailed:
+	case StatusCompleted, StatusFailed, StatusCancelled, StatusDeadLetter:
 		t.CompletedAt = &now
 	}
 
@@ -77,3 +188,63 @@ func (t *Task) UpdateStatus(status TaskStatus) {
 		t.DurationStr = endTime.Sub(*t.StartedAt).String()
 	}
 }
+
+// Cancel moves the task to the cancelled status and records why
+func (t *Task) Cancel(reason string) {
+	t.UpdateStatus(StatusCancelled)
+	t.CancelledAt = t.CompletedAt
+	t.CancelReason = reason
+}
+
+// Clone returns a deep copy of the task, so the copy can be changed and read
+// without affecting the original
+func (t *Task) Clone() *Task {
+	if t == nil {
+		return nil
+	}
+
+	clone := *t
+	clone.Payload = cloneRaw(t.Payload)
+	clone.Result = cloneRaw(t.Result)
+	clone.StartedAt = cloneTime(t.StartedAt)
+	clone.CompletedAt = cloneTime(t.CompletedAt)
+	clone.CancelledAt = cloneTime(t.CancelledAt)
+	clone.NextRetryAt = cloneTime(t.NextRetryAt)
+	clone.RunAt = cloneTime(t.RunAt)
+	clone.AttemptLog = slices.Clone(t.AttemptLog)
+	clone.DependsOn = slices.Clone(t.DependsOn)
+
+	if t.Progress != nil {
+		progress := *t.Progress
+		progress.ETA = cloneTime(t.Progress.ETA)
+		clone.Progress = &progress
+	}
+
+	if t.History != nil {
+		clone.History = make([]TaskEdit, len(t.History))
+		for i, edit := range t.History {
+			changes := make([]FieldChange, len(edit.Changes))
+			for j, change := range edit.Changes {
+				changes[j] = FieldChange{Field: change.Field, From: cloneRaw(change.From), To: cloneRaw(change.To)}
+			}
+			clone.History[i] = TaskEdit{EditedAt: edit.EditedAt, Changes: changes}
+		}
+	}
+
+	return &clone
+}
+
+func cloneRaw(data json.RawMessage) json.RawMessage {
+	if data == nil {
+		return nil
+	}
+	return append(json.RawMessage{}, data...)
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
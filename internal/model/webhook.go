@@ -0,0 +1,81 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// WebhookEvent is the name of the webhook event sent when a task moves to
+// the status, such as "task.completed"
+func WebhookEvent(status TaskStatus) string {
+	return "task." + string(status)
+}
+
+// WebhookSubscription sends the listed events of every task to URL
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Clone returns a deep copy of the subscription
+func (w *WebhookSubscription) Clone() *WebhookSubscription {
+	if w == nil {
+		return nil
+	}
+
+	clone := *w
+	clone.Events = slices.Clone(w.Events)
+	return &clone
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a single event sent to a single URL, with every attempt
+// made to send it
+type WebhookDelivery struct {
+	ID     string `json:"id"`
+	TaskID string `json:"task_id"`
+	// SubscriptionID is empty for deliveries to the task's callback URL
+	SubscriptionID string `json:"subscription_id,omitempty"`
+	URL            string `json:"url"`
+	Event          string `json:"event"`
+	// Payload is the request body, fixed when the event happened so every
+	// attempt sends the same task state
+	Payload       json.RawMessage   `json:"payload"`
+	Status        DeliveryStatus    `json:"status"`
+	Attempts      []DeliveryAttempt `json:"attempts,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"`
+}
+
+// DeliveryAttempt records the outcome of one attempt to send a delivery
+type DeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	SentAt     time.Time `json:"sent_at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Clone returns a deep copy of the delivery
+func (d *WebhookDelivery) Clone() *WebhookDelivery {
+	if d == nil {
+		return nil
+	}
+
+	clone := *d
+	clone.Payload = cloneRaw(d.Payload)
+	clone.Attempts = slices.Clone(d.Attempts)
+	clone.DeliveredAt = cloneTime(d.DeliveredAt)
+	clone.NextAttemptAt = cloneTime(d.NextAttemptAt)
+	return &clone
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// WorkerState tells whether a worker is running a task
+type WorkerState string
+
+const (
+	WorkerIdle WorkerState = "idle"
+	WorkerBusy WorkerState = "busy"
+)
+
+// Worker is a snapshot of a worker of the task service
+type Worker struct {
+	ID        int
+	State     WorkerState
+	StartedAt time.Time
+	// TaskID and TaskStartedAt describe the task a busy worker runs
+	TaskID        string
+	TaskStartedAt *time.Time
+	// TasksProcessed counts the tasks the worker has finished
+	TasksProcessed int
+	// Retiring is set for a retired worker that has not stopped yet; a busy
+	// one stops once its task is done
+	Retiring bool
+}
+
+// ScalingDecision records a resize of the worker pool by the autoscaler and
+// the load that caused it
+type ScalingDecision struct {
+	At          time.Time
+	From        int
+	To          int
+	Reason      string
+	QueueDepth  int
+	OldestWait  time.Duration
+	BusyWorkers int
+}
+
+// AutoscalerStatus describes the load the autoscaler last observed and the
+// decisions it made
+type AutoscalerStatus struct {
+	Enabled     bool
+	MinWorkers  int
+	MaxWorkers  int
+	Workers     int
+	BusyWorkers int
+	QueueDepth  int
+	OldestWait  time.Duration
+	Evaluations int
+	ScaleUps    int
+	ScaleDowns  int
+	// Decisions holds the most recent decisions, oldest first
+	Decisions []ScalingDecision
+}
@@ -0,0 +1,33 @@
+package model
+
+// WorkflowStatus summarises the statuses of the tasks of a workflow
+type WorkflowStatus string
+
+const (
+	// WorkflowRunning means some tasks have not finished yet
+	WorkflowRunning WorkflowStatus = "running"
+	// WorkflowCompleted means every task completed
+	WorkflowCompleted WorkflowStatus = "completed"
+	// WorkflowFailed means every task finished but some did not complete
+	WorkflowFailed WorkflowStatus = "failed"
+)
+
+// Workflow is a graph of tasks submitted together, linked by their dependencies
+type Workflow struct {
+	ID    string
+	Tasks []*Task
+}
+
+// Status derives the status of the workflow from its tasks
+func (w *Workflow) Status() WorkflowStatus {
+	status := WorkflowCompleted
+	for _, task := range w.Tasks {
+		switch {
+		case !task.Status.IsTerminal():
+			return WorkflowRunning
+		case task.Status != StatusCompleted:
+			status = WorkflowFailed
+		}
+	}
+	return status
+}
@@ -0,0 +1,772 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+	lockFileName     = "LOCK"
+)
+
+type walOp string
+
+const (
+	walOpCreate      walOp = "create"
+	walOpCreateBatch walOp = "create_batch"
+	walOpUpdate      walOp = "update"
+	walOpDelete      walOp = "delete"
+
+	walOpCreateSchedule walOp = "create_schedule"
+	walOpUpdateSchedule walOp = "update_schedule"
+	walOpDeleteSchedule walOp = "delete_schedule"
+
+	walOpCreateWebhook   walOp = "create_webhook"
+	walOpDeleteWebhook   walOp = "delete_webhook"
+	walOpCreateDelivery  walOp = "create_delivery"
+	walOpUpdateDelivery  walOp = "update_delivery"
+	walOpPruneDeliveries walOp = "prune_deliveries"
+)
+
+// walEntry is a single record of the write-ahead log
+type walEntry struct {
+	Op       walOp                      `json:"op"`
+	Task     *model.Task                `json:"task,omitempty"`
+	Tasks    []*model.Task              `json:"tasks,omitempty"`
+	Schedule *model.Schedule            `json:"schedule,omitempty"`
+	Webhook  *model.WebhookSubscription `json:"webhook,omitempty"`
+	Delivery *model.WebhookDelivery     `json:"delivery,omitempty"`
+	ID       string                     `json:"id,omitempty"`
+	IDs      []string                   `json:"ids,omitempty"`
+}
+
+// snapshot is the compacted state of the repository
+type snapshot struct {
+	NextID         int64                        `json:"next_id"`
+	Tasks          []*model.Task                `json:"tasks"`
+	NextScheduleID int64                        `json:"next_schedule_id,omitempty"`
+	Schedules      []*model.Schedule            `json:"schedules,omitempty"`
+	NextWebhookID  int64                        `json:"next_webhook_id,omitempty"`
+	Webhooks       []*model.WebhookSubscription `json:"webhooks,omitempty"`
+	NextDeliveryID int64                        `json:"next_delivery_id,omitempty"`
+	Deliveries     []*model.WebhookDelivery     `json:"deliveries,omitempty"`
+}
+
+// FileRepositoryConfig holds file repository configuration
+type FileRepositoryConfig struct {
+	Dir             string
+	CompactInterval time.Duration
+}
+
+// FileTaskRepository keeps tasks, schedules and webhooks in memory and makes them durable
+// by appending every write to a write-ahead log. The log is periodically
+// compacted into a snapshot, and snapshot plus log are replayed on startup.
+type FileTaskRepository struct {
+	tasks           map[string]*model.Task
+	idempotencyKeys idempotencyIndex
+	workflows       workflowIndex
+	schedules       map[string]*model.Schedule
+	webhooks        map[string]*model.WebhookSubscription
+	deliveries      map[string]*model.WebhookDelivery
+	mu              sync.RWMutex
+	nextID          int64
+	nextScheduleID  int64
+	nextWebhookID   int64
+	nextDeliveryID  int64
+	dir             string
+	wal             *os.File
+	// walErr is set when a failed append could not be cut off the log again.
+	// Writes are refused until a compaction starts a new log.
+	walErr   error
+	lock     *os.File
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewFileTaskRepository opens the repository in config.Dir, creating it if needed.
+// The directory is locked until Close is called.
+func NewFileTaskRepository(config FileRepositoryConfig) (*FileTaskRepository, error) {
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "create data directory")
+	}
+
+	lock, err := lockDir(filepath.Join(config.Dir, lockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &FileTaskRepository{
+		tasks:           make(map[string]*model.Task),
+		idempotencyKeys: make(idempotencyIndex),
+		workflows:       make(workflowIndex),
+		schedules:       make(map[string]*model.Schedule),
+		webhooks:        make(map[string]*model.WebhookSubscription),
+		deliveries:      make(map[string]*model.WebhookDelivery),
+		nextID:          1,
+		nextScheduleID:  1,
+		nextWebhookID:   1,
+		nextDeliveryID:  1,
+		dir:             config.Dir,
+		lock:            lock,
+		stopChan:        make(chan struct{}),
+	}
+
+	if err := r.load(); err != nil {
+		unlockDir(lock)
+		return nil, err
+	}
+
+	if config.CompactInterval > 0 {
+		r.wg.Add(1)
+		go r.compactLoop(config.CompactInterval)
+	}
+
+	return r, nil
+}
+
+// load replays the snapshot and the write-ahead log and opens the log for appending
+func (r *FileTaskRepository) load() error {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFileName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return errors.Wrap(err, "read snapshot")
+	default:
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return errors.Wrap(err, "parse snapshot")
+		}
+		r.nextID = snap.NextID
+		for _, task := range snap.Tasks {
+			r.apply(walEntry{Op: walOpCreate, Task: task})
+		}
+		if snap.NextScheduleID > r.nextScheduleID {
+			r.nextScheduleID = snap.NextScheduleID
+		}
+		for _, schedule := range snap.Schedules {
+			r.apply(walEntry{Op: walOpCreateSchedule, Schedule: schedule})
+		}
+		r.nextWebhookID = max(r.nextWebhookID, snap.NextWebhookID)
+		for _, webhook := range snap.Webhooks {
+			r.apply(walEntry{Op: walOpCreateWebhook, Webhook: webhook})
+		}
+		r.nextDeliveryID = max(r.nextDeliveryID, snap.NextDeliveryID)
+		for _, delivery := range snap.Deliveries {
+			r.apply(walEntry{Op: walOpCreateDelivery, Delivery: delivery})
+		}
+	}
+
+	wal, err := os.OpenFile(filepath.Join(r.dir, walFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "open wal")
+	}
+
+	valid, err := r.replay(wal)
+	if err != nil {
+		wal.Close()
+		return err
+	}
+
+	// Drop a torn record left by a crash in the middle of an append
+	if err := wal.Truncate(valid); err != nil {
+		wal.Close()
+		return errors.Wrap(err, "truncate wal")
+	}
+	if _, err := wal.Seek(valid, io.SeekStart); err != nil {
+		wal.Close()
+		return errors.Wrap(err, "seek wal")
+	}
+
+	r.wal = wal
+	return nil
+}
+
+// replay applies every complete record of the log and returns the offset
+// just past the last one. Only the last record may be torn, by a crash in
+// the middle of an append; a broken record before it means the log is corrupt.
+func (r *FileTaskRepository) replay(wal *os.File) (int64, error) {
+	reader := bufio.NewReader(wal)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "read wal")
+		}
+
+		var entry walEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+				return offset, nil
+			}
+			return 0, errors.Wrapf(ErrCorruptWAL, "record at offset %d", offset)
+		}
+
+		r.apply(entry)
+		offset += int64(len(line))
+	}
+}
+
+// apply applies a log record to the in-memory state. Records are idempotent,
+// so replaying a log that was already compacted into the snapshot is harmless.
+func (r *FileTaskRepository) apply(entry walEntry) {
+	switch entry.Op {
+	case walOpCreate, walOpUpdate:
+		if entry.Task == nil {
+			return
+		}
+		if previous, exists := r.tasks[entry.Task.ID]; exists {
+			r.idempotencyKeys.replace(previous, entry.Task, r.tasks)
+			r.workflows.remove(previous)
+		}
+		r.tasks[entry.Task.ID] = entry.Task
+		r.idempotencyKeys.add(entry.Task)
+		r.workflows.add(entry.Task)
+		r.nextID = nextSequenceID(entry.Task.ID, r.nextID)
+	case walOpCreateBatch:
+		for _, task := range entry.Tasks {
+			r.apply(walEntry{Op: walOpCreate, Task: task})
+		}
+	case walOpDelete:
+		if task, exists := r.tasks[entry.ID]; exists {
+			delete(r.tasks, entry.ID)
+			r.idempotencyKeys.remove(task, r.tasks)
+			r.workflows.remove(task)
+		}
+	case walOpCreateSchedule, walOpUpdateSchedule:
+		if entry.Schedule == nil {
+			return
+		}
+		r.schedules[entry.Schedule.ID] = entry.Schedule
+		r.nextScheduleID = nextSequenceID(entry.Schedule.ID, r.nextScheduleID)
+	case walOpDeleteSchedule:
+		delete(r.schedules, entry.ID)
+	case walOpCreateWebhook:
+		if entry.Webhook == nil {
+			return
+		}
+		r.webhooks[entry.Webhook.ID] = entry.Webhook
+		r.nextWebhookID = nextSequenceID(entry.Webhook.ID, r.nextWebhookID)
+	case walOpDeleteWebhook:
+		delete(r.webhooks, entry.ID)
+	case walOpCreateDelivery, walOpUpdateDelivery:
+		if entry.Delivery == nil {
+			return
+		}
+		r.deliveries[entry.Delivery.ID] = entry.Delivery
+		r.nextDeliveryID = nextSequenceID(entry.Delivery.ID, r.nextDeliveryID)
+	case walOpPruneDeliveries:
+		for _, id := range entry.IDs {
+			delete(r.deliveries, id)
+		}
+	}
+}
+
+// nextSequenceID returns the next ID to hand out once id is taken
+func nextSequenceID(id string, next int64) int64 {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && n >= next {
+		return n + 1
+	}
+	return next
+}
+
+// appendEntry writes a record to the log and syncs it to disk. A record that
+// failed to be written or synced is cut off again, so the records after it
+// do not follow a torn one. The caller must hold r.mu.
+func (r *FileTaskRepository) appendEntry(entry walEntry) error {
+	if r.walErr != nil {
+		return errors.Wrap(r.walErr, "wal unusable until the next compaction")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "encode wal entry")
+	}
+
+	offset, err := r.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.Wrap(err, "seek wal")
+	}
+
+	if _, err := r.wal.Write(append(data, '\n')); err != nil {
+		r.rollback(offset)
+		return errors.Wrap(err, "write wal")
+	}
+
+	if err := r.wal.Sync(); err != nil {
+		r.rollback(offset)
+		return errors.Wrap(err, "sync wal")
+	}
+	return nil
+}
+
+// rollback cuts the log back to offset after a failed append
+func (r *FileTaskRepository) rollback(offset int64) {
+	if err := r.wal.Truncate(offset); err != nil {
+		r.walErr = err
+		return
+	}
+	if _, err := r.wal.Seek(offset, io.SeekStart); err != nil {
+		r.walErr = err
+	}
+}
+
+func (r *FileTaskRepository) CreateTask(task *model.Task) (*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task.ID = strconv.FormatInt(r.nextID, 10)
+	task.Version = 1
+	if err := r.appendEntry(walEntry{Op: walOpCreate, Task: task}); err != nil {
+		return nil, err
+	}
+
+	r.nextID++
+	r.tasks[task.ID] = task.Clone()
+	r.idempotencyKeys.add(task)
+	r.workflows.add(task)
+	return task, nil
+}
+
+// CreateTasks stores the tasks with a single log record, so a crash keeps
+// either all of them or none
+func (r *FileTaskRepository) CreateTasks(tasks []*model.Task) ([]*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, task := range tasks {
+		task.ID = strconv.FormatInt(r.nextID+int64(i), 10)
+		task.Version = 1
+		if err := resolveBatchRefs(tasks, i); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.appendEntry(walEntry{Op: walOpCreateBatch, Tasks: tasks}); err != nil {
+		return nil, err
+	}
+
+	r.nextID += int64(len(tasks))
+	for _, task := range tasks {
+		r.tasks[task.ID] = task.Clone()
+		r.idempotencyKeys.add(task)
+		r.workflows.add(task)
+	}
+	return tasks, nil
+}
+
+func (r *FileTaskRepository) ListTasks() ([]*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := make([]*model.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		tasks = append(tasks, task.Clone())
+	}
+	return tasks, nil
+}
+
+func (r *FileTaskRepository) QueryTasks(query TaskQuery) ([]*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return queryTasks(r.tasks, query), nil
+}
+
+func (r *FileTaskRepository) GetTask(id string) (*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, exists := r.tasks[id]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	return task.Clone(), nil
+}
+
+func (r *FileTaskRepository) FindTaskByIdempotencyKey(key string) (*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.idempotencyKeys.find(r.tasks, key)
+}
+
+func (r *FileTaskRepository) ListWorkflowTasks(workflowID string) ([]*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.workflows.list(r.tasks, workflowID), nil
+}
+
+func (r *FileTaskRepository) UpdateTask(task *model.Task) (*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, exists := r.tasks[task.ID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	if task.Version != previous.Version {
+		return nil, ErrVersionConflict
+	}
+
+	task.Version++
+	if err := r.appendEntry(walEntry{Op: walOpUpdate, Task: task}); err != nil {
+		task.Version--
+		return nil, err
+	}
+
+	r.tasks[task.ID] = task.Clone()
+	r.idempotencyKeys.replace(previous, task, r.tasks)
+	r.workflows.replace(previous, task)
+	return task, nil
+}
+
+func (r *FileTaskRepository) DeleteTask(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, exists := r.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpDelete, ID: id}); err != nil {
+		return err
+	}
+
+	delete(r.tasks, id)
+	r.idempotencyKeys.remove(task, r.tasks)
+	r.workflows.remove(task)
+	return nil
+}
+
+func (r *FileTaskRepository) CreateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedule.ID = strconv.FormatInt(r.nextScheduleID, 10)
+	if err := r.appendEntry(walEntry{Op: walOpCreateSchedule, Schedule: schedule}); err != nil {
+		return nil, err
+	}
+
+	r.nextScheduleID++
+	r.schedules[schedule.ID] = schedule.Clone()
+	return schedule, nil
+}
+
+func (r *FileTaskRepository) ListSchedules() ([]*model.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]*model.Schedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, schedule.Clone())
+	}
+	return schedules, nil
+}
+
+func (r *FileTaskRepository) GetSchedule(id string) (*model.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedule, exists := r.schedules[id]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule.Clone(), nil
+}
+
+func (r *FileTaskRepository) UpdateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schedules[schedule.ID]; !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpUpdateSchedule, Schedule: schedule}); err != nil {
+		return nil, err
+	}
+
+	r.schedules[schedule.ID] = schedule.Clone()
+	return schedule, nil
+}
+
+func (r *FileTaskRepository) DeleteSchedule(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schedules[id]; !exists {
+		return ErrScheduleNotFound
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpDeleteSchedule, ID: id}); err != nil {
+		return err
+	}
+
+	delete(r.schedules, id)
+	return nil
+}
+
+func (r *FileTaskRepository) CreateWebhook(webhook *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = strconv.FormatInt(r.nextWebhookID, 10)
+	if err := r.appendEntry(walEntry{Op: walOpCreateWebhook, Webhook: webhook}); err != nil {
+		return nil, err
+	}
+
+	r.nextWebhookID++
+	r.webhooks[webhook.ID] = webhook.Clone()
+	return webhook, nil
+}
+
+func (r *FileTaskRepository) ListWebhooks() ([]*model.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]*model.WebhookSubscription, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, webhook.Clone())
+	}
+	return webhooks, nil
+}
+
+func (r *FileTaskRepository) DeleteWebhook(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpDeleteWebhook, ID: id}); err != nil {
+		return err
+	}
+
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *FileTaskRepository) CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.ID = strconv.FormatInt(r.nextDeliveryID, 10)
+	if err := r.appendEntry(walEntry{Op: walOpCreateDelivery, Delivery: delivery}); err != nil {
+		return nil, err
+	}
+
+	r.nextDeliveryID++
+	r.deliveries[delivery.ID] = delivery.Clone()
+	return delivery, nil
+}
+
+func (r *FileTaskRepository) GetDelivery(id string) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery.Clone(), nil
+}
+
+func (r *FileTaskRepository) UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[delivery.ID]; !exists {
+		return nil, ErrDeliveryNotFound
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpUpdateDelivery, Delivery: delivery}); err != nil {
+		return nil, err
+	}
+
+	r.deliveries[delivery.ID] = delivery.Clone()
+	return delivery, nil
+}
+
+func (r *FileTaskRepository) ListDeliveries(taskID string) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return filterDeliveries(r.deliveries, func(delivery *model.WebhookDelivery) bool {
+		return delivery.TaskID == taskID
+	}), nil
+}
+
+func (r *FileTaskRepository) ListPendingDeliveries() ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return filterDeliveries(r.deliveries, func(delivery *model.WebhookDelivery) bool {
+		return delivery.Status == model.DeliveryPending
+	}), nil
+}
+
+// PruneDeliveries logs the removed deliveries in a single record
+func (r *FileTaskRepository) PruneDeliveries(before time.Time, keep int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := prunableDeliveries(r.deliveries, before, keep)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := r.appendEntry(walEntry{Op: walOpPruneDeliveries, IDs: ids}); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		delete(r.deliveries, id)
+	}
+	return len(ids), nil
+}
+
+// Compact writes the current state to a new snapshot and truncates the log
+func (r *FileTaskRepository) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snap := snapshot{
+		NextID:         r.nextID,
+		Tasks:          make([]*model.Task, 0, len(r.tasks)),
+		NextScheduleID: r.nextScheduleID,
+		Schedules:      make([]*model.Schedule, 0, len(r.schedules)),
+		NextWebhookID:  r.nextWebhookID,
+		Webhooks:       make([]*model.WebhookSubscription, 0, len(r.webhooks)),
+		NextDeliveryID: r.nextDeliveryID,
+		Deliveries:     make([]*model.WebhookDelivery, 0, len(r.deliveries)),
+	}
+	for _, task := range r.tasks {
+		snap.Tasks = append(snap.Tasks, task)
+	}
+	for _, schedule := range r.schedules {
+		snap.Schedules = append(snap.Schedules, schedule)
+	}
+	for _, webhook := range r.webhooks {
+		snap.Webhooks = append(snap.Webhooks, webhook)
+	}
+	for _, delivery := range r.deliveries {
+		snap.Deliveries = append(snap.Deliveries, delivery)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return errors.Wrap(err, "encode snapshot")
+	}
+
+	// The log may only be truncated once the snapshot replacing it is on disk
+	if err := writeFileAtomic(filepath.Join(r.dir, snapshotFileName), data); err != nil {
+		return err
+	}
+
+	if r.walErr != nil {
+		return r.reopenWAL()
+	}
+
+	if err := r.wal.Truncate(0); err != nil {
+		return errors.Wrap(err, "truncate wal")
+	}
+	_, err = r.wal.Seek(0, io.SeekStart)
+	return errors.Wrap(err, "seek wal")
+}
+
+// reopenWAL replaces a log that could not be repaired after a failed append
+// with an empty one. The snapshot holds everything the old log did.
+func (r *FileTaskRepository) reopenWAL() error {
+	wal, err := os.OpenFile(filepath.Join(r.dir, walFileName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "reopen wal")
+	}
+
+	r.wal.Close()
+	r.wal = wal
+	r.walErr = nil
+	return nil
+}
+
+// compactLoop compacts the log every interval until Close is called
+func (r *FileTaskRepository) compactLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			// A failed compaction leaves the log intact, so it is retried on the next tick
+			_ = r.Compact()
+		}
+	}
+}
+
+// Close compacts the log one last time and releases the data directory
+func (r *FileTaskRepository) Close() error {
+	close(r.stopChan)
+	r.wg.Wait()
+
+	err := r.Compact()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if closeErr := r.wal.Close(); err == nil {
+		err = errors.Wrap(closeErr, "close wal")
+	}
+	unlockDir(r.lock)
+
+	return err
+}
+
+// writeFileAtomic replaces the file at path so that readers see either the old
+// or the new contents, never a partial write. The directory is synced after
+// the rename, so the new file is durable before the caller relies on it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "create temp file")
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return errors.Wrap(err, "write temp file")
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return errors.Wrap(err, "sync temp file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close temp file")
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "rename temp file")
+	}
+	return syncDir(filepath.Dir(path))
+}
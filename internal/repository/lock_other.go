@@ -0,0 +1,30 @@
+//go:build !unix
+
+package repository
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// lockDir creates the lock file exclusively so that only one process can use
+// the data directory at a time. A lock file left behind by a crashed process
+// has to be removed by hand.
+func lockDir(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrRepositoryLocked
+		}
+		return nil, errors.Wrap(err, "lock data directory")
+	}
+
+	return f, nil
+}
+
+// unlockDir releases the lock taken by lockDir
+func unlockDir(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package repository
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lockDir takes an exclusive advisory lock on the lock file so that only one
+// process can use the data directory at a time
+func lockDir(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open lock file")
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrRepositoryLocked
+		}
+		return nil, errors.Wrap(err, "lock data directory")
+	}
+
+	return f, nil
+}
+
+// unlockDir releases the lock taken by lockDir
+func unlockDir(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	f.Close()
+}
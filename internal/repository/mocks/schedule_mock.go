@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: schedule.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	model "github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// MockScheduleRepositoryInterface is a mock of ScheduleRepositoryInterface interface.
+type MockScheduleRepositoryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockScheduleRepositoryInterfaceMockRecorder
+}
+
+// MockScheduleRepositoryInterfaceMockRecorder is the mock recorder for MockScheduleRepositoryInterface.
+type MockScheduleRepositoryInterfaceMockRecorder struct {
+	mock *MockScheduleRepositoryInterface
+}
+
+// NewMockScheduleRepositoryInterface creates a new mock instance.
+func NewMockScheduleRepositoryInterface(ctrl *gomock.Controller) *MockScheduleRepositoryInterface {
+	mock := &MockScheduleRepositoryInterface{ctrl: ctrl}
+	mock.recorder = &MockScheduleRepositoryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScheduleRepositoryInterface) EXPECT() *MockScheduleRepositoryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CreateSchedule mocks base method.
+func (m *MockScheduleRepositoryInterface) CreateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", schedule)
+	ret0, _ := ret[0].(*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockScheduleRepositoryInterfaceMockRecorder) CreateSchedule(schedule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockScheduleRepositoryInterface)(nil).CreateSchedule), schedule)
+}
+
+// DeleteSchedule mocks base method.
+func (m *MockScheduleRepositoryInterface) DeleteSchedule(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSchedule", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSchedule indicates an expected call of DeleteSchedule.
+func (mr *MockScheduleRepositoryInterfaceMockRecorder) DeleteSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSchedule", reflect.TypeOf((*MockScheduleRepositoryInterface)(nil).DeleteSchedule), id)
+}
+
+// GetSchedule mocks base method.
+func (m *MockScheduleRepositoryInterface) GetSchedule(id string) (*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", id)
+	ret0, _ := ret[0].(*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockScheduleRepositoryInterfaceMockRecorder) GetSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockScheduleRepositoryInterface)(nil).GetSchedule), id)
+}
+
+// ListSchedules mocks base method.
+func (m *MockScheduleRepositoryInterface) ListSchedules() ([]*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules")
+	ret0, _ := ret[0].([]*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockScheduleRepositoryInterfaceMockRecorder) ListSchedules() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockScheduleRepositoryInterface)(nil).ListSchedules))
+}
+
+// UpdateSchedule mocks base method.
+func (m *MockScheduleRepositoryInterface) UpdateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSchedule", schedule)
+	ret0, _ := ret[0].(*model.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockScheduleRepositoryInterfaceMockRecorder) UpdateSchedule(schedule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockScheduleRepositoryInterface)(nil).UpdateSchedule), schedule)
+}
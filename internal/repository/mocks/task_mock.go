@@ -9,6 +9,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/nessibeliyeltay/task-api/internal/model"
+	repository "github.com/nessibeliyeltay/task-api/internal/repository"
 )
 
 // MockTaskRepositoryInterface is a mock of TaskRepositoryInterface interface.
@@ -49,6 +50,21 @@ func (mr *MockTaskRepositoryInterfaceMockRecorder) CreateTask(task interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).CreateTask), task)
 }
 
+// CreateTasks mocks base method.
+func (m *MockTaskRepositoryInterface) CreateTasks(tasks []*model.Task) ([]*model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTasks", tasks)
+	ret0, _ := ret[0].([]*model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTasks indicates an expected call of CreateTasks.
+func (mr *MockTaskRepositoryInterfaceMockRecorder) CreateTasks(tasks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTasks", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).CreateTasks), tasks)
+}
+
 // DeleteTask mocks base method.
 func (m *MockTaskRepositoryInterface) DeleteTask(id string) error {
 	m.ctrl.T.Helper()
@@ -63,6 +79,21 @@ func (mr *MockTaskRepositoryInterfaceMockRecorder) DeleteTask(id interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTask", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).DeleteTask), id)
 }
 
+// FindTaskByIdempotencyKey mocks base method.
+func (m *MockTaskRepositoryInterface) FindTaskByIdempotencyKey(key string) (*model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTaskByIdempotencyKey", key)
+	ret0, _ := ret[0].(*model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTaskByIdempotencyKey indicates an expected call of FindTaskByIdempotencyKey.
+func (mr *MockTaskRepositoryInterfaceMockRecorder) FindTaskByIdempotencyKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTaskByIdempotencyKey", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).FindTaskByIdempotencyKey), key)
+}
+
 // GetTask mocks base method.
 func (m *MockTaskRepositoryInterface) GetTask(id string) (*model.Task, error) {
 	m.ctrl.T.Helper()
@@ -93,6 +124,36 @@ func (mr *MockTaskRepositoryInterfaceMockRecorder) ListTasks() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTasks", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).ListTasks))
 }
 
+// ListWorkflowTasks mocks base method.
+func (m *MockTaskRepositoryInterface) ListWorkflowTasks(workflowID string) ([]*model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWorkflowTasks", workflowID)
+	ret0, _ := ret[0].([]*model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWorkflowTasks indicates an expected call of ListWorkflowTasks.
+func (mr *MockTaskRepositoryInterfaceMockRecorder) ListWorkflowTasks(workflowID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWorkflowTasks", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).ListWorkflowTasks), workflowID)
+}
+
+// QueryTasks mocks base method.
+func (m *MockTaskRepositoryInterface) QueryTasks(query repository.TaskQuery) ([]*model.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryTasks", query)
+	ret0, _ := ret[0].([]*model.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryTasks indicates an expected call of QueryTasks.
+func (mr *MockTaskRepositoryInterfaceMockRecorder) QueryTasks(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryTasks", reflect.TypeOf((*MockTaskRepositoryInterface)(nil).QueryTasks), query)
+}
+
 // UpdateTask mocks base method.
 func (m *MockTaskRepositoryInterface) UpdateTask(task *model.Task) (*model.Task, error) {
 	m.ctrl.T.Helper()
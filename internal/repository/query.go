@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// TaskSort is the field a task query is ordered by
+type TaskSort string
+
+const (
+	SortByCreatedAt TaskSort = "created_at"
+	SortByPriority  TaskSort = "priority"
+)
+
+// IsValid reports whether s is one of the supported sort fields
+func (s TaskSort) IsValid() bool {
+	switch s {
+	case SortByCreatedAt, SortByPriority:
+		return true
+	default:
+		return false
+	}
+}
+
+// TaskQuery selects a page of tasks. Empty filters match every task.
+//
+// Tasks are ordered by the sort field and then by ID, so the order is total
+// and a page can continue after the last task of the previous one. Tasks
+// created between two pages therefore never shift the following pages.
+type TaskQuery struct {
+	Statuses      []model.TaskStatus
+	Types         []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          TaskSort
+	Descending    bool
+	// After continues the listing after this position
+	After *TaskCursor
+	// Limit caps the number of returned tasks; 0 returns all of them
+	Limit int
+}
+
+// TaskCursor is the position of a task in the order of a TaskQuery
+type TaskCursor struct {
+	CreatedAt time.Time
+	Priority  int
+	ID        int64
+}
+
+// CursorOf returns the position of the task
+func CursorOf(task *model.Task) TaskCursor {
+	id, _ := strconv.ParseInt(task.ID, 10, 64)
+	return TaskCursor{CreatedAt: task.CreatedAt, Priority: task.Priority, ID: id}
+}
+
+// sortField returns the query's sort field, defaulting to the creation time
+func (q TaskQuery) sortField() TaskSort {
+	if q.Sort == "" {
+		return SortByCreatedAt
+	}
+	return q.Sort
+}
+
+// match reports whether the task passes the filters of the query
+func (q TaskQuery) match(task *model.Task) bool {
+	if q.CreatedAfter != nil && !task.CreatedAt.After(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && !task.CreatedAt.Before(*q.CreatedBefore) {
+		return false
+	}
+	return containsOrEmpty(q.Statuses, task.Status) && containsOrEmpty(q.Types, task.Type)
+}
+
+// compare orders two positions the way the query lists them
+func (q TaskQuery) compare(a, b TaskCursor) int {
+	var c int
+	if q.sortField() == SortByPriority {
+		c = cmp.Compare(a.Priority, b.Priority)
+	} else {
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if q.Descending {
+		return -c
+	}
+	return c
+}
+
+// queryTasks runs the query against the tasks of an in-memory backend
+func queryTasks(tasks map[string]*model.Task, query TaskQuery) []*model.Task {
+	result := make([]*model.Task, 0)
+	for _, task := range tasks {
+		if !query.match(task) {
+			continue
+		}
+		if query.After != nil && query.compare(CursorOf(task), *query.After) <= 0 {
+			continue
+		}
+		result = append(result, task)
+	}
+
+	slices.SortFunc(result, func(a, b *model.Task) int {
+		return query.compare(CursorOf(a), CursorOf(b))
+	})
+
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+	for i, task := range result {
+		result[i] = task.Clone()
+	}
+	return result
+}
+
+func containsOrEmpty[T comparable](values []T, value T) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
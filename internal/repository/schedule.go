@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+//go:generate mockgen -source=schedule.go -destination=mocks/schedule_mock.go -package=mocks
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+type ScheduleRepositoryInterface interface {
+	CreateSchedule(schedule *model.Schedule) (*model.Schedule, error)
+	ListSchedules() ([]*model.Schedule, error)
+	GetSchedule(id string) (*model.Schedule, error)
+	UpdateSchedule(schedule *model.Schedule) (*model.Schedule, error)
+	DeleteSchedule(id string) error
+}
+
+type InMemoryScheduleRepository struct {
+	schedules map[string]*model.Schedule
+	mu        sync.RWMutex
+	nextID    int64
+}
+
+func NewScheduleRepository() ScheduleRepositoryInterface {
+	return &InMemoryScheduleRepository{
+		schedules: make(map[string]*model.Schedule),
+		nextID:    1,
+	}
+}
+
+func (r *InMemoryScheduleRepository) CreateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedule.ID = strconv.FormatInt(r.nextID, 10)
+	r.nextID++
+	r.schedules[schedule.ID] = schedule.Clone()
+	return schedule, nil
+}
+
+func (r *InMemoryScheduleRepository) ListSchedules() ([]*model.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]*model.Schedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, schedule.Clone())
+	}
+	return schedules, nil
+}
+
+func (r *InMemoryScheduleRepository) GetSchedule(id string) (*model.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedule, exists := r.schedules[id]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule.Clone(), nil
+}
+
+func (r *InMemoryScheduleRepository) UpdateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schedules[schedule.ID]; !exists {
+		return nil, ErrScheduleNotFound
+	}
+
+	r.schedules[schedule.ID] = schedule.Clone()
+	return schedule, nil
+}
+
+func (r *InMemoryScheduleRepository) DeleteSchedule(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schedules[id]; !exists {
+		return ErrScheduleNotFound
+	}
+
+	delete(r.schedules, id)
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+func newSchedule(name string) *model.Schedule {
+	next := time.Now().Add(time.Hour).Truncate(time.Second)
+	return &model.Schedule{
+		Name:            name,
+		Cron:            "@hourly",
+		Timezone:        "UTC",
+		Template:        model.TaskTemplate{Title: "Task", Description: "Description"},
+		MissedRunPolicy: model.MissedRunSkip,
+		CreatedAt:       time.Now(),
+		NextRunAt:       &next,
+	}
+}
+
+// testScheduleRepository проверяет поведение, общее для всех реализаций ScheduleRepositoryInterface
+func testScheduleRepository(t *testing.T, newRepo func(t *testing.T) ScheduleRepositoryInterface) {
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		schedule, err := repo.CreateSchedule(newSchedule("Nightly"))
+		require.NoError(t, err)
+		assert.Equal(t, "1", schedule.ID)
+
+		got, err := repo.GetSchedule(schedule.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Nightly", got.Name)
+		assert.Equal(t, "Task", got.Template.Title)
+		assert.True(t, schedule.NextRunAt.Equal(*got.NextRunAt))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.CreateSchedule(newSchedule("Schedule"))
+			require.NoError(t, err)
+		}
+
+		schedules, err := repo.ListSchedules()
+		require.NoError(t, err)
+		assert.Len(t, schedules, 3)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		schedule, err := repo.CreateSchedule(newSchedule("Schedule"))
+		require.NoError(t, err)
+
+		schedule.Paused = true
+		schedule.NextRunAt = nil
+		_, err = repo.UpdateSchedule(schedule)
+		require.NoError(t, err)
+
+		got, err := repo.GetSchedule(schedule.ID)
+		require.NoError(t, err)
+		assert.True(t, got.Paused)
+		assert.Nil(t, got.NextRunAt)
+
+		require.NoError(t, repo.DeleteSchedule(schedule.ID))
+		_, err = repo.GetSchedule(schedule.ID)
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetSchedule("42")
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+
+		_, err = repo.UpdateSchedule(&model.Schedule{ID: "42"})
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+
+		err = repo.DeleteSchedule("42")
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+	})
+}
+
+func TestInMemoryScheduleRepository(t *testing.T) {
+	testScheduleRepository(t, func(_ *testing.T) ScheduleRepositoryInterface {
+		return NewScheduleRepository()
+	})
+}
+
+func TestFileScheduleRepository(t *testing.T) {
+	testScheduleRepository(t, func(t *testing.T) ScheduleRepositoryInterface {
+		repo := openFileRepository(t, t.TempDir())
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSQLiteScheduleRepository(t *testing.T) {
+	testScheduleRepository(t, func(t *testing.T) ScheduleRepositoryInterface {
+		repo, err := NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: filepath.Join(t.TempDir(), "tasks.db")})
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestFileScheduleRepositoryReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+	first, err := repo.CreateSchedule(newSchedule("First"))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Compact())
+	second, err := repo.CreateSchedule(newSchedule("Second"))
+	require.NoError(t, err)
+
+	// Имитируем падение процесса: второе расписание есть только в WAL
+	repo.wal.Close()
+	unlockDir(repo.lock)
+
+	repo = openFileRepository(t, dir)
+	defer repo.Close()
+
+	got, err := repo.GetSchedule(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", got.Name)
+
+	got, err = repo.GetSchedule(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Second", got.Name)
+
+	third, err := repo.CreateSchedule(newSchedule("Third"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", third.ID)
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// Relevance weights of the indexed task fields
+const (
+	titleWeight       = 3
+	descriptionWeight = 2
+	resultWeight      = 1
+	errorWeight       = 1
+)
+
+// prefixMatchWeight scales the score of a term that only matches a query term
+// by prefix, so whole-word matches rank first
+const prefixMatchWeight = 0.5
+
+// SearchResult is a task found by a full-text search
+type SearchResult struct {
+	Task  *model.Task
+	Score float64
+}
+
+// SearchHit is the ID and score of a task matching a search
+type SearchHit struct {
+	ID    string
+	Score float64
+}
+
+// TaskSearcher is implemented by repositories that support full-text search
+type TaskSearcher interface {
+	// SearchTasks returns at most limit tasks matching the query, best match first
+	SearchTasks(query string, limit int) ([]SearchResult, error)
+}
+
+// TaskIndex is an inverted index over the title, description, result and
+// error of tasks. Each term maps to the tasks containing it, weighted by how
+// often and in which fields it occurs.
+type TaskIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]float64
+	// terms holds the keys of postings in order, for prefix lookups
+	terms []string
+	docs  map[string][]string
+}
+
+func NewTaskIndex() *TaskIndex {
+	return &TaskIndex{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string][]string),
+	}
+}
+
+// Add indexes the task, replacing an earlier version of it
+func (x *TaskIndex) Add(task *model.Task) {
+	weights := make(map[string]float64)
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{task.Title, titleWeight},
+		{task.Description, descriptionWeight},
+		{string(task.Result), resultWeight},
+		{task.Error, errorWeight},
+	} {
+		for _, term := range tokenize(field.text) {
+			weights[term] += field.weight
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(task.ID)
+
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		postings, ok := x.postings[term]
+		if !ok {
+			postings = make(map[string]float64)
+			x.postings[term] = postings
+			i, _ := slices.BinarySearch(x.terms, term)
+			x.terms = slices.Insert(x.terms, i, term)
+		}
+		postings[task.ID] = weight
+		terms = append(terms, term)
+	}
+	if len(terms) > 0 {
+		x.docs[task.ID] = terms
+	}
+}
+
+// Remove drops the task from the index
+func (x *TaskIndex) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(id)
+}
+
+func (x *TaskIndex) remove(id string) {
+	for _, term := range x.docs[id] {
+		postings := x.postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(x.postings, term)
+			if i, ok := slices.BinarySearch(x.terms, term); ok {
+				x.terms = slices.Delete(x.terms, i, i+1)
+			}
+		}
+	}
+	delete(x.docs, id)
+}
+
+// Search returns the IDs and scores of at most limit tasks matching the query,
+// best match first. Every query term matches the indexed terms it is a prefix
+// of. Scores add up the weighted term frequencies, scaled by how rare a term
+// is and by the share of query terms a task matches.
+func (x *TaskIndex) Search(query string, limit int) []SearchHit {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	total := float64(len(x.docs))
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+
+	for _, queryTerm := range queryTerms {
+		best := make(map[string]float64)
+
+		start := sort.SearchStrings(x.terms, queryTerm)
+		for _, term := range x.terms[start:] {
+			if !strings.HasPrefix(term, queryTerm) {
+				break
+			}
+
+			postings := x.postings[term]
+			idf := math.Log(1 + total/float64(len(postings)))
+			if term != queryTerm {
+				idf *= prefixMatchWeight
+			}
+			for id, weight := range postings {
+				best[id] = max(best[id], weight*idf)
+			}
+		}
+
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		coverage := float64(matched[id]) / float64(len(queryTerms))
+		hits = append(hits, SearchHit{ID: id, Score: score * coverage})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return compareIDs(hits[i].ID, hits[j].ID) < 0
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// tokenize splits text into lower case words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// compareIDs orders numeric task IDs by value
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// IndexedTaskRepository keeps a full-text index of the tasks of another
+// repository. The index lives in memory only and is rebuilt from the
+// repository when it is created.
+type IndexedTaskRepository struct {
+	TaskRepositoryInterface
+	index *TaskIndex
+	// mu keeps the order of index updates in line with the repository writes
+	mu sync.Mutex
+}
+
+// NewIndexedTaskRepository indexes every task of repo and keeps the index up to
+// date with the writes made through the returned repository
+func NewIndexedTaskRepository(repo TaskRepositoryInterface) (*IndexedTaskRepository, error) {
+	tasks, err := repo.ListTasks()
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	index := NewTaskIndex()
+	for _, task := range tasks {
+		index.Add(task)
+	}
+
+	return &IndexedTaskRepository{TaskRepositoryInterface: repo, index: index}, nil
+}
+
+func (r *IndexedTaskRepository) CreateTask(task *model.Task) (*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created, err := r.TaskRepositoryInterface.CreateTask(task)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	r.index.Add(created)
+	return created, nil
+}
+
+func (r *IndexedTaskRepository) CreateTasks(tasks []*model.Task) ([]*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created, err := r.TaskRepositoryInterface.CreateTasks(tasks)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	for _, task := range created {
+		r.index.Add(task)
+	}
+	return created, nil
+}
+
+func (r *IndexedTaskRepository) UpdateTask(task *model.Task) (*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated, err := r.TaskRepositoryInterface.UpdateTask(task)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	r.index.Add(updated)
+	return updated, nil
+}
+
+func (r *IndexedTaskRepository) DeleteTask(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.TaskRepositoryInterface.DeleteTask(id); err != nil {
+		return err //nolint:wrapcheck
+	}
+	r.index.Remove(id)
+	return nil
+}
+
+func (r *IndexedTaskRepository) SearchTasks(query string, limit int) ([]SearchResult, error) {
+	hits := r.index.Search(query, limit)
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		task, err := r.GetTask(hit.ID)
+		if errors.Is(err, ErrTaskNotFound) {
+			// Deleted since the search
+			continue
+		}
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		results = append(results, SearchResult{Task: task, Score: hit.Score})
+	}
+	return results, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+func TestTaskIndex(t *testing.T) {
+	index := NewTaskIndex()
+	index.Add(&model.Task{ID: "1", Title: "Invoice export", Description: "Export March invoices to CSV"})
+	index.Add(&model.Task{ID: "2", Title: "Report", Description: "Monthly report", Error: "invoice service unavailable"})
+	index.Add(&model.Task{ID: "3", Title: "Cleanup", Description: "Remove exported files", Result: []byte(`{"removed":12}`)})
+
+	searchIDs := func(query string) []string {
+		var ids []string
+		for _, hit := range index.Search(query, 0) {
+			ids = append(ids, hit.ID)
+		}
+		return ids
+	}
+
+	// Совпадение в заголовке важнее совпадения в ошибке
+	assert.Equal(t, []string{"1", "2"}, searchIDs("invoice"))
+
+	// Поиск по префиксу и без учёта регистра
+	assert.Equal(t, []string{"1", "3"}, searchIDs("EXPORT"))
+	assert.Equal(t, []string{"1", "2"}, searchIDs("invo"))
+
+	// Задачи, совпавшие с большим числом слов запроса, идут первыми
+	ids := searchIDs("that task about the invoice export")
+	require.Len(t, ids, 3)
+	assert.Equal(t, "1", ids[0])
+
+	assert.Equal(t, []string{"3"}, searchIDs("removed"))
+	assert.Empty(t, searchIDs("   "))
+	assert.Empty(t, searchIDs("missing"))
+
+	// Повторная индексация заменяет старые слова задачи
+	index.Add(&model.Task{ID: "1", Title: "Payroll"})
+	assert.Equal(t, []string{"2"}, searchIDs("invoice"))
+	assert.Equal(t, []string{"1"}, searchIDs("payroll"))
+
+	index.Remove("1")
+	assert.Empty(t, searchIDs("payroll"))
+	assert.Equal(t, []string{"3"}, searchIDs("export"))
+}
+
+func TestIndexedTaskRepository(t *testing.T) {
+	base := NewTaskRepository()
+	_, err := base.CreateTask(model.NewTask("Invoice export", "", "default", nil))
+	require.NoError(t, err)
+
+	// Индекс строится из уже сохранённых задач
+	repo, err := NewIndexedTaskRepository(base)
+	require.NoError(t, err)
+
+	results, err := repo.SearchTasks("invoice", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].Task.ID)
+	assert.Positive(t, results[0].Score)
+
+	task, err := repo.CreateTask(model.NewTask("Report", "", "default", nil))
+	require.NoError(t, err)
+
+	task.UpdateStatus(model.StatusFailed)
+	task.Error = "invoice service unavailable"
+	_, err = repo.UpdateTask(task)
+	require.NoError(t, err)
+
+	results, err = repo.SearchTasks("invoice", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].Task.ID)
+	assert.Equal(t, "2", results[1].Task.ID)
+
+	results, err = repo.SearchTasks("invoice", 1)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	require.NoError(t, repo.DeleteTask("1"))
+	results, err = repo.SearchTasks("invoice", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "2", results[0].Task.ID)
+}
@@ -0,0 +1,718 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite" // pure Go SQLite driver, registered as "sqlite"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// migrations are applied in order, each one exactly once. The version of a
+// migration is its index plus one, so existing entries must never be edited or
+// reordered; add a new entry instead.
+var migrations = []string{
+	`CREATE TABLE tasks (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		title        TEXT    NOT NULL,
+		description  TEXT    NOT NULL,
+		type         TEXT    NOT NULL,
+		status       TEXT    NOT NULL,
+		created_at   INTEGER NOT NULL,
+		started_at   INTEGER,
+		completed_at INTEGER,
+		error        TEXT    NOT NULL DEFAULT '',
+		data         TEXT    NOT NULL
+	);
+	CREATE INDEX idx_tasks_status ON tasks (status);
+	CREATE INDEX idx_tasks_created_at ON tasks (created_at);`,
+	`CREATE TABLE schedules (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		name        TEXT    NOT NULL,
+		cron        TEXT    NOT NULL,
+		paused      INTEGER NOT NULL,
+		next_run_at INTEGER,
+		data        TEXT    NOT NULL
+	);`,
+	`ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+	UPDATE tasks SET priority = COALESCE(json_extract(data, '$.priority'), 0);
+	CREATE INDEX idx_tasks_type ON tasks (type);
+	CREATE INDEX idx_tasks_priority ON tasks (priority);`,
+	`ALTER TABLE tasks ADD COLUMN idempotency_key TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_tasks_idempotency_key ON tasks (idempotency_key) WHERE idempotency_key != '';`,
+	`ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 0;`,
+	`CREATE TABLE webhooks (
+		id   INTEGER PRIMARY KEY AUTOINCREMENT,
+		url  TEXT    NOT NULL,
+		data TEXT    NOT NULL
+	);
+	CREATE TABLE webhook_deliveries (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id    TEXT    NOT NULL,
+		status     TEXT    NOT NULL,
+		created_at INTEGER NOT NULL,
+		data       TEXT    NOT NULL
+	);
+	CREATE INDEX idx_webhook_deliveries_task_id ON webhook_deliveries (task_id);
+	CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status);`,
+	`ALTER TABLE tasks ADD COLUMN workflow_id TEXT NOT NULL DEFAULT '';
+	UPDATE tasks SET workflow_id = COALESCE(json_extract(data, '$.workflow_id'), '');
+	CREATE INDEX idx_tasks_workflow_id ON tasks (workflow_id) WHERE workflow_id != '';`,
+}
+
+// SQLiteRepositoryConfig holds SQLite repository configuration
+type SQLiteRepositoryConfig struct {
+	Path string
+}
+
+// SQLiteTaskRepository stores tasks, schedules and webhooks in an embedded SQLite database.
+// The columns hold the fields that are useful to query by, while the data
+// column keeps the complete record as JSON.
+type SQLiteTaskRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTaskRepository opens the database at config.Path, creating it and
+// its directory if needed, and brings its schema up to date
+func NewSQLiteTaskRepository(config SQLiteRepositoryConfig) (*SQLiteTaskRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o750); err != nil {
+		return nil, errors.Wrap(err, "create database directory")
+	}
+
+	dsn := "file:" + config.Path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "open database")
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY between our own writes
+	db.SetMaxOpenConns(1)
+
+	r := &SQLiteTaskRepository{db: db}
+	if err := r.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// migrate applies every migration newer than the schema version of the database
+func (r *SQLiteTaskRepository) migrate() error {
+	if _, err := r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return errors.Wrap(err, "create schema_migrations")
+	}
+
+	var current int
+	if err := r.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return errors.Wrap(err, "read schema version")
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+
+		tx, err := r.db.Begin()
+		if err != nil {
+			return errors.Wrap(err, "begin migration")
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return errors.Wrapf(err, "apply migration %d", version)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			version, time.Now().Unix()); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return errors.Wrapf(err, "record migration %d", version)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errors.Wrapf(err, "commit migration %d", version)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLiteTaskRepository) CreateTask(task *model.Task) (*model.Task, error) {
+	if err := insertTask(r.db, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// CreateTasks inserts the tasks in a single transaction
+func (r *SQLiteTaskRepository) CreateTasks(tasks []*model.Task) ([]*model.Task, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+
+	for i, task := range tasks {
+		if err := resolveBatchRefs(tasks, i); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return nil, err
+		}
+		if err := insertTask(tx, task); err != nil {
+			tx.Rollback() //nolint:errcheck
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "commit transaction")
+	}
+	return tasks, nil
+}
+
+// execer runs statements on the database or within a transaction
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertTask inserts the task and sets its ID
+func insertTask(db execer, task *model.Task) error {
+	task.Version = 1
+	data, err := json.Marshal(task)
+	if err != nil {
+		return errors.Wrap(err, "encode task")
+	}
+
+	res, err := db.Exec(`INSERT INTO tasks
+		(title, description, type, status, priority, idempotency_key, workflow_id, created_at, started_at, completed_at, error, data, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		task.Title, task.Description, task.Type, string(task.Status), task.Priority, task.IdempotencyKey, task.WorkflowID,
+		task.CreatedAt.UnixNano(), nullableTime(task.StartedAt), nullableTime(task.CompletedAt),
+		task.Error, string(data), task.Version)
+	if err != nil {
+		return errors.Wrap(err, "insert task")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return errors.Wrap(err, "read task id")
+	}
+
+	task.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+func (r *SQLiteTaskRepository) ListTasks() ([]*model.Task, error) {
+	rows, err := r.db.Query(`SELECT id, data FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query tasks")
+	}
+	defer rows.Close()
+
+	tasks := make([]*model.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, errors.Wrap(rows.Err(), "iterate tasks")
+}
+
+func (r *SQLiteTaskRepository) QueryTasks(query TaskQuery) ([]*model.Task, error) {
+	var (
+		where []string
+		args  []any
+	)
+
+	if len(query.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(query.Statuses))+")")
+		for _, status := range query.Statuses {
+			args = append(args, string(status))
+		}
+	}
+	if len(query.Types) > 0 {
+		where = append(where, "type IN ("+placeholders(len(query.Types))+")")
+		for _, taskType := range query.Types {
+			args = append(args, taskType)
+		}
+	}
+	if query.CreatedAfter != nil {
+		where = append(where, "created_at > ?")
+		args = append(args, query.CreatedAfter.UnixNano())
+	}
+	if query.CreatedBefore != nil {
+		where = append(where, "created_at < ?")
+		args = append(args, query.CreatedBefore.UnixNano())
+	}
+
+	column, direction, next := "created_at", "ASC", ">"
+	if query.sortField() == SortByPriority {
+		column = "priority"
+	}
+	if query.Descending {
+		direction, next = "DESC", "<"
+	}
+
+	if after := query.After; after != nil {
+		value := any(after.CreatedAt.UnixNano())
+		if column == "priority" {
+			value = after.Priority
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, next))
+		args = append(args, value, value, after.ID)
+	}
+
+	stmt := `SELECT id, data FROM tasks`
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", column, direction)
+	if query.Limit > 0 {
+		stmt += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := r.db.Query(stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query tasks")
+	}
+	defer rows.Close()
+
+	tasks := make([]*model.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, errors.Wrap(rows.Err(), "iterate tasks")
+}
+
+func (r *SQLiteTaskRepository) GetTask(id string) (*model.Task, error) {
+	task, err := scanTask(r.db.QueryRow(`SELECT id, data FROM tasks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	return task, err
+}
+
+func (r *SQLiteTaskRepository) FindTaskByIdempotencyKey(key string) (*model.Task, error) {
+	if key == "" {
+		return nil, ErrTaskNotFound
+	}
+
+	task, err := scanTask(r.db.QueryRow(`SELECT id, data FROM tasks
+		WHERE idempotency_key = ? ORDER BY id DESC LIMIT 1`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	return task, err
+}
+
+func (r *SQLiteTaskRepository) ListWorkflowTasks(workflowID string) ([]*model.Task, error) {
+	if workflowID == "" {
+		return []*model.Task{}, nil
+	}
+
+	rows, err := r.db.Query(`SELECT id, data FROM tasks WHERE workflow_id = ? ORDER BY id`, workflowID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query workflow tasks")
+	}
+	defer rows.Close()
+
+	tasks := make([]*model.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, errors.Wrap(rows.Err(), "iterate tasks")
+}
+
+// UpdateTask only matches the row at the task's version. If none matches,
+// the task is looked up to tell a missing task from a conflicting update.
+func (r *SQLiteTaskRepository) UpdateTask(task *model.Task) (*model.Task, error) {
+	version := task.Version
+	task.Version++
+	data, err := json.Marshal(task)
+	task.Version = version
+	if err != nil {
+		return nil, errors.Wrap(err, "encode task")
+	}
+
+	res, err := r.db.Exec(`UPDATE tasks SET
+		title = ?, description = ?, type = ?, status = ?, priority = ?, created_at = ?,
+		started_at = ?, completed_at = ?, error = ?, data = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		task.Title, task.Description, task.Type, string(task.Status), task.Priority,
+		task.CreatedAt.UnixNano(), nullableTime(task.StartedAt), nullableTime(task.CompletedAt),
+		task.Error, string(data), task.ID, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "update task")
+	}
+
+	if err := requireAffected(res, ErrVersionConflict); err != nil {
+		if _, getErr := r.GetTask(task.ID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, err
+	}
+
+	task.Version++
+	return task, nil
+}
+
+func (r *SQLiteTaskRepository) DeleteTask(id string) error {
+	res, err := r.db.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "delete task")
+	}
+
+	return requireAffected(res, ErrTaskNotFound)
+}
+
+func (r *SQLiteTaskRepository) CreateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode schedule")
+	}
+
+	res, err := r.db.Exec(`INSERT INTO schedules (name, cron, paused, next_run_at, data)
+		VALUES (?, ?, ?, ?, ?)`,
+		schedule.Name, schedule.Cron, schedule.Paused, nullableTime(schedule.NextRunAt), string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "insert schedule")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "read schedule id")
+	}
+
+	schedule.ID = strconv.FormatInt(id, 10)
+	return schedule, nil
+}
+
+func (r *SQLiteTaskRepository) ListSchedules() ([]*model.Schedule, error) {
+	rows, err := r.db.Query(`SELECT id, data FROM schedules ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query schedules")
+	}
+	defer rows.Close()
+
+	schedules := make([]*model.Schedule, 0)
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, errors.Wrap(rows.Err(), "iterate schedules")
+}
+
+func (r *SQLiteTaskRepository) GetSchedule(id string) (*model.Schedule, error) {
+	schedule, err := scanSchedule(r.db.QueryRow(`SELECT id, data FROM schedules WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	return schedule, err
+}
+
+func (r *SQLiteTaskRepository) UpdateSchedule(schedule *model.Schedule) (*model.Schedule, error) {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode schedule")
+	}
+
+	res, err := r.db.Exec(`UPDATE schedules SET
+		name = ?, cron = ?, paused = ?, next_run_at = ?, data = ?
+		WHERE id = ?`,
+		schedule.Name, schedule.Cron, schedule.Paused, nullableTime(schedule.NextRunAt),
+		string(data), schedule.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "update schedule")
+	}
+
+	if err := requireAffected(res, ErrScheduleNotFound); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (r *SQLiteTaskRepository) DeleteSchedule(id string) error {
+	res, err := r.db.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "delete schedule")
+	}
+
+	return requireAffected(res, ErrScheduleNotFound)
+}
+
+func (r *SQLiteTaskRepository) CreateWebhook(webhook *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode webhook")
+	}
+
+	res, err := r.db.Exec(`INSERT INTO webhooks (url, data) VALUES (?, ?)`, webhook.URL, string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "insert webhook")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "read webhook id")
+	}
+
+	webhook.ID = strconv.FormatInt(id, 10)
+	return webhook, nil
+}
+
+func (r *SQLiteTaskRepository) ListWebhooks() ([]*model.WebhookSubscription, error) {
+	rows, err := r.db.Query(`SELECT id, data FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query webhooks")
+	}
+	defer rows.Close()
+
+	webhooks := make([]*model.WebhookSubscription, 0)
+	for rows.Next() {
+		var webhook model.WebhookSubscription
+		if err := scanRecord(rows, &webhook.ID, &webhook); err != nil {
+			return nil, errors.Wrap(err, "scan webhook")
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+
+	return webhooks, errors.Wrap(rows.Err(), "iterate webhooks")
+}
+
+func (r *SQLiteTaskRepository) DeleteWebhook(id string) error {
+	res, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "delete webhook")
+	}
+
+	return requireAffected(res, ErrWebhookNotFound)
+}
+
+func (r *SQLiteTaskRepository) CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode delivery")
+	}
+
+	res, err := r.db.Exec(`INSERT INTO webhook_deliveries (task_id, status, created_at, data)
+		VALUES (?, ?, ?, ?)`,
+		delivery.TaskID, string(delivery.Status), delivery.CreatedAt.UnixNano(), string(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "insert delivery")
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "read delivery id")
+	}
+
+	delivery.ID = strconv.FormatInt(id, 10)
+	return delivery, nil
+}
+
+func (r *SQLiteTaskRepository) GetDelivery(id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := scanRecord(r.db.QueryRow(`SELECT id, data FROM webhook_deliveries WHERE id = ?`, id), &delivery.ID, &delivery)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "scan delivery")
+	}
+	return &delivery, nil
+}
+
+func (r *SQLiteTaskRepository) UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode delivery")
+	}
+
+	res, err := r.db.Exec(`UPDATE webhook_deliveries SET status = ?, data = ? WHERE id = ?`,
+		string(delivery.Status), string(data), delivery.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "update delivery")
+	}
+
+	if err := requireAffected(res, ErrDeliveryNotFound); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (r *SQLiteTaskRepository) ListDeliveries(taskID string) ([]*model.WebhookDelivery, error) {
+	return r.queryDeliveries(`SELECT id, data FROM webhook_deliveries WHERE task_id = ? ORDER BY id`, taskID)
+}
+
+func (r *SQLiteTaskRepository) ListPendingDeliveries() ([]*model.WebhookDelivery, error) {
+	return r.queryDeliveries(`SELECT id, data FROM webhook_deliveries WHERE status = ? ORDER BY id`,
+		string(model.DeliveryPending))
+}
+
+func (r *SQLiteTaskRepository) queryDeliveries(query string, args ...any) ([]*model.WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+		if err := scanRecord(rows, &delivery.ID, &delivery); err != nil {
+			return nil, errors.Wrap(err, "scan delivery")
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	return deliveries, errors.Wrap(rows.Err(), "iterate deliveries")
+}
+
+// PruneDeliveries removes the finished deliveries created before the cutoff
+// and those beyond the keep most recent finished ones in a single statement
+func (r *SQLiteTaskRepository) PruneDeliveries(before time.Time, keep int) (int, error) {
+	cutoff := int64(math.MinInt64)
+	if !before.IsZero() {
+		cutoff = before.UnixNano()
+	}
+	// A negative limit keeps every finished delivery
+	limit := -1
+	if keep > 0 {
+		limit = keep
+	}
+
+	res, err := r.db.Exec(`DELETE FROM webhook_deliveries
+		WHERE status != ? AND (created_at < ? OR id NOT IN (
+			SELECT id FROM webhook_deliveries WHERE status != ? ORDER BY id DESC LIMIT ?))`,
+		string(model.DeliveryPending), cutoff, string(model.DeliveryPending), limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "prune deliveries")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "read affected rows")
+	}
+	return int(n), nil
+}
+
+// Close closes the database
+func (r *SQLiteTaskRepository) Close() error {
+	return errors.Wrap(r.db.Close(), "close database")
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner) (*model.Task, error) {
+	var (
+		id   int64
+		data string
+	)
+	if err := row.Scan(&id, &data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "scan task")
+	}
+
+	var task model.Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		return nil, errors.Wrap(err, "decode task")
+	}
+
+	task.ID = strconv.FormatInt(id, 10)
+	return &task, nil
+}
+
+func scanSchedule(row rowScanner) (*model.Schedule, error) {
+	var (
+		id   int64
+		data string
+	)
+	if err := row.Scan(&id, &data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "scan schedule")
+	}
+
+	var schedule model.Schedule
+	if err := json.Unmarshal([]byte(data), &schedule); err != nil {
+		return nil, errors.Wrap(err, "decode schedule")
+	}
+
+	schedule.ID = strconv.FormatInt(id, 10)
+	return &schedule, nil
+}
+
+// scanRecord reads a row of an id and a JSON data column into record and
+// sets its ID. It returns sql.ErrNoRows unwrapped.
+func scanRecord(row rowScanner, id *string, record any) error {
+	var (
+		rowID int64
+		data  string
+	)
+	if err := row.Scan(&rowID, &data); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := json.Unmarshal([]byte(data), record); err != nil {
+		return errors.Wrap(err, "decode record")
+	}
+
+	*id = strconv.FormatInt(rowID, 10)
+	return nil
+}
+
+// requireAffected returns notFound if the statement did not touch any row
+func requireAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "read affected rows")
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// placeholders returns n comma separated query parameters
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func nullableTime(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package repository
+
+// syncDir is a no-op where directories can not be synced; a rename is made
+// durable by the file system itself there
+func syncDir(string) error {
+	return nil
+}
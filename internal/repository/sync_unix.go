@@ -0,0 +1,21 @@
+//go:build unix
+
+package repository
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// syncDir flushes the directory entries of dir, so a file renamed into it
+// survives a crash
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "open directory")
+	}
+	defer f.Close()
+
+	return errors.Wrap(f.Sync(), "sync directory")
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/nessibeliyeltay/task-api/internal/model"
@@ -10,26 +13,79 @@ import (
 
 //go:generate mockgen -source=task.go -destination=mocks/task_mock.go -package=mocks
 
-var ErrTaskNotFound = errors.New("task not found")
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrRepositoryLocked = errors.New("data directory is locked by another process")
+	ErrCorruptWAL       = errors.New("write-ahead log is corrupt")
+	ErrVersionConflict  = errors.New("task was changed concurrently")
+	ErrInvalidBatchRef  = errors.New("invalid batch reference")
+)
+
+// batchRefPrefix marks a dependency on another task of the same CreateTasks call
+const batchRefPrefix = "batch:"
 
+// BatchRef refers to the task at index of the tasks passed to CreateTasks.
+// A task may list it in DependsOn to depend on a task before it in the same
+// call; CreateTasks replaces it with the ID that task is stored with.
+func BatchRef(index int) string {
+	return batchRefPrefix + strconv.Itoa(index)
+}
+
+// resolveBatchRefs replaces the batch references among the dependencies of
+// tasks[i] with the IDs of the earlier tasks they refer to
+func resolveBatchRefs(tasks []*model.Task, i int) error {
+	for j, id := range tasks[i].DependsOn {
+		ref, ok := strings.CutPrefix(id, batchRefPrefix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(ref)
+		if err != nil || n < 0 || n >= i {
+			return fmt.Errorf("%w: %q in task %d", ErrInvalidBatchRef, id, i)
+		}
+		tasks[i].DependsOn[j] = tasks[n].ID
+	}
+	return nil
+}
+
+// TaskRepositoryInterface stores tasks. Every implementation keeps its own
+// copy of the tasks it is given and returns copies, so callers may change the
+// tasks they pass in or get back without affecting the stored ones.
 type TaskRepositoryInterface interface {
 	CreateTask(task *model.Task) (*model.Task, error)
+	// CreateTasks stores all of the tasks or, if one of them can not be
+	// stored, none of them. Dependencies given as BatchRef are replaced with
+	// the IDs of the tasks they refer to.
+	CreateTasks(tasks []*model.Task) ([]*model.Task, error)
 	ListTasks() ([]*model.Task, error)
+	// QueryTasks returns the tasks that pass the query's filters, in its order
+	QueryTasks(query TaskQuery) ([]*model.Task, error)
 	GetTask(id string) (*model.Task, error)
+	// FindTaskByIdempotencyKey returns the most recent task created with the key
+	FindTaskByIdempotencyKey(key string) (*model.Task, error)
+	// ListWorkflowTasks returns the tasks of a workflow in the order they were created
+	ListWorkflowTasks(workflowID string) ([]*model.Task, error)
+	// UpdateTask stores the task only if the stored task still has the
+	// task's version, and returns ErrVersionConflict otherwise. On success it
+	// advances the version of both.
 	UpdateTask(task *model.Task) (*model.Task, error)
 	DeleteTask(id string) error
 }
 
 type InMemoryTaskRepository struct {
-	tasks  map[string]*model.Task
-	mu     sync.RWMutex
-	nextID int64
+	tasks           map[string]*model.Task
+	idempotencyKeys idempotencyIndex
+	workflows       workflowIndex
+	mu              sync.RWMutex
+	nextID          int64
 }
 
 func NewTaskRepository() TaskRepositoryInterface {
 	return &InMemoryTaskRepository{
-		tasks:  make(map[string]*model.Task),
-		nextID: 1,
+		tasks:           make(map[string]*model.Task),
+		idempotencyKeys: make(idempotencyIndex),
+		workflows:       make(workflowIndex),
+		nextID:          1,
 	}
 }
 
@@ -38,10 +94,34 @@ func (r *InMemoryTaskRepository) CreateTask(task *model.Task) (*model.Task, erro
 	defer r.mu.Unlock()
 
 	task.ID = r.getNextID()
-	r.tasks[task.ID] = task
+	task.Version = 1
+	r.tasks[task.ID] = task.Clone()
+	r.idempotencyKeys.add(task)
+	r.workflows.add(task)
 	return task, nil
 }
 
+func (r *InMemoryTaskRepository) CreateTasks(tasks []*model.Task) ([]*model.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, task := range tasks {
+		task.ID = strconv.FormatInt(r.nextID+int64(i), 10)
+		if err := resolveBatchRefs(tasks, i); err != nil {
+			return nil, err
+		}
+	}
+
+	r.nextID += int64(len(tasks))
+	for _, task := range tasks {
+		task.Version = 1
+		r.tasks[task.ID] = task.Clone()
+		r.idempotencyKeys.add(task)
+		r.workflows.add(task)
+	}
+	return tasks, nil
+}
+
 func (r *InMemoryTaskRepository) getNextID() string {
 	id := r.nextID
 	r.nextID++
@@ -54,11 +134,18 @@ func (r *InMemoryTaskRepository) ListTasks() ([]*model.Task, error) {
 
 	tasks := make([]*model.Task, 0, len(r.tasks))
 	for _, task := range r.tasks {
-		tasks = append(tasks, task)
+		tasks = append(tasks, task.Clone())
 	}
 	return tasks, nil
 }
 
+func (r *InMemoryTaskRepository) QueryTasks(query TaskQuery) ([]*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return queryTasks(r.tasks, query), nil
+}
+
 func (r *InMemoryTaskRepository) GetTask(id string) (*model.Task, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -67,18 +154,135 @@ func (r *InMemoryTaskRepository) GetTask(id string) (*model.Task, error) {
 	if !exists {
 		return nil, ErrTaskNotFound
 	}
-	return task, nil
+	return task.Clone(), nil
+}
+
+func (r *InMemoryTaskRepository) FindTaskByIdempotencyKey(key string) (*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.idempotencyKeys.find(r.tasks, key)
+}
+
+// idempotencyIndex maps each idempotency key of an in-memory backend to the
+// most recent task created with it, so keyed creates need no scan
+type idempotencyIndex map[string]string
+
+// add indexes the key of a stored task
+func (i idempotencyIndex) add(task *model.Task) {
+	if task.IdempotencyKey == "" {
+		return
+	}
+	if id, ok := i[task.IdempotencyKey]; !ok || compareIDs(task.ID, id) > 0 {
+		i[task.IdempotencyKey] = task.ID
+	}
+}
+
+// remove drops the key of a task that left the store. If the task was the
+// most recent one with its key, the next most recent of tasks takes its place.
+func (i idempotencyIndex) remove(task *model.Task, tasks map[string]*model.Task) {
+	if task.IdempotencyKey == "" || i[task.IdempotencyKey] != task.ID {
+		return
+	}
+
+	delete(i, task.IdempotencyKey)
+	for _, other := range tasks {
+		if other.ID != task.ID && other.IdempotencyKey == task.IdempotencyKey {
+			i.add(other)
+		}
+	}
+}
+
+// replace reindexes a task whose stored version changes from previous to task
+func (i idempotencyIndex) replace(previous, task *model.Task, tasks map[string]*model.Task) {
+	if previous.IdempotencyKey == task.IdempotencyKey {
+		return
+	}
+	i.remove(previous, tasks)
+	i.add(task)
+}
+
+func (i idempotencyIndex) find(tasks map[string]*model.Task, key string) (*model.Task, error) {
+	task, ok := tasks[i[key]]
+	if key == "" || !ok {
+		return nil, ErrTaskNotFound
+	}
+	return task.Clone(), nil
+}
+
+func (r *InMemoryTaskRepository) ListWorkflowTasks(workflowID string) ([]*model.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.workflows.list(r.tasks, workflowID), nil
+}
+
+// workflowIndex maps each workflow of an in-memory backend to the IDs of its
+// tasks, so a workflow is read without a scan
+type workflowIndex map[string]map[string]struct{}
+
+// add indexes a stored task under its workflow
+func (i workflowIndex) add(task *model.Task) {
+	if task.WorkflowID == "" {
+		return
+	}
+	if i[task.WorkflowID] == nil {
+		i[task.WorkflowID] = make(map[string]struct{})
+	}
+	i[task.WorkflowID][task.ID] = struct{}{}
+}
+
+// remove drops a task that left the store from its workflow
+func (i workflowIndex) remove(task *model.Task) {
+	ids, ok := i[task.WorkflowID]
+	if !ok {
+		return
+	}
+	delete(ids, task.ID)
+	if len(ids) == 0 {
+		delete(i, task.WorkflowID)
+	}
+}
+
+// replace reindexes a task whose stored version changes from previous to task
+func (i workflowIndex) replace(previous, task *model.Task) {
+	if previous.WorkflowID == task.WorkflowID {
+		return
+	}
+	i.remove(previous)
+	i.add(task)
+}
+
+// list returns copies of the workflow's tasks in the order they were created
+func (i workflowIndex) list(tasks map[string]*model.Task, workflowID string) []*model.Task {
+	ids := i[workflowID]
+	result := make([]*model.Task, 0, len(ids))
+	for id := range ids {
+		result = append(result, tasks[id].Clone())
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		return compareIDs(result[a].ID, result[b].ID) < 0
+	})
+	return result
 }
 
 func (r *InMemoryTaskRepository) UpdateTask(task *model.Task) (*model.Task, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.tasks[task.ID]; !exists {
+	previous, exists := r.tasks[task.ID]
+	if !exists {
 		return nil, ErrTaskNotFound
 	}
+	if task.Version != previous.Version {
+		return nil, ErrVersionConflict
+	}
 
-	r.tasks[task.ID] = task
+	task.Version++
+	r.tasks[task.ID] = task.Clone()
+	r.idempotencyKeys.replace(previous, task, r.tasks)
+	r.workflows.replace(previous, task)
 	return task, nil
 }
 
@@ -86,10 +290,13 @@ func (r *InMemoryTaskRepository) DeleteTask(id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.tasks[id]; !exists {
+	task, exists := r.tasks[id]
+	if !exists {
 		return ErrTaskNotFound
 	}
 
 	delete(r.tasks, id)
+	r.idempotencyKeys.remove(task, r.tasks)
+	r.workflows.remove(task)
 	return nil
 }
@@ -0,0 +1,581 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// testRepository проверяет поведение, общее для всех реализаций TaskRepositoryInterface
+func testRepository(t *testing.T, newRepo func(t *testing.T) TaskRepositoryInterface) {
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+
+		task, err := repo.CreateTask(model.NewTask("Task", "Description", "default", nil))
+		require.NoError(t, err)
+		assert.Equal(t, "1", task.ID)
+
+		got, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Task", got.Title)
+		assert.Equal(t, "Description", got.Description)
+		assert.Equal(t, model.StatusPending, got.Status)
+	})
+
+	t.Run("sequential ids", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first, err := repo.CreateTask(model.NewTask("First", "", "default", nil))
+		require.NoError(t, err)
+		second, err := repo.CreateTask(model.NewTask("Second", "", "default", nil))
+		require.NoError(t, err)
+
+		assert.Equal(t, "1", first.ID)
+		assert.Equal(t, "2", second.ID)
+	})
+
+	t.Run("create batch", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.CreateTask(model.NewTask("Single", "", "default", nil))
+		require.NoError(t, err)
+
+		keyed := model.NewTask("Second", "", "default", nil)
+		keyed.IdempotencyKey = "batch"
+		tasks, err := repo.CreateTasks([]*model.Task{model.NewTask("First", "", "default", nil), keyed})
+		require.NoError(t, err)
+		require.Len(t, tasks, 2)
+		assert.Equal(t, "2", tasks[0].ID)
+		assert.Equal(t, "3", tasks[1].ID)
+
+		got, err := repo.GetTask("3")
+		require.NoError(t, err)
+		assert.Equal(t, "Second", got.Title)
+
+		got, err = repo.FindTaskByIdempotencyKey("batch")
+		require.NoError(t, err)
+		assert.Equal(t, "3", got.ID)
+
+		// Хранилище держит свои копии задач пакета
+		tasks[0].Title = "Changed"
+		got, err = repo.GetTask("2")
+		require.NoError(t, err)
+		assert.Equal(t, "First", got.Title)
+
+		// Задача пакета может зависеть от предыдущей задачи того же пакета
+		dependent := model.NewTask("Dependent", "", "default", nil)
+		dependent.DependsOn = []string{"1", BatchRef(0)}
+		tasks, err = repo.CreateTasks([]*model.Task{model.NewTask("Dependency", "", "default", nil), dependent})
+		require.NoError(t, err)
+		got, err = repo.GetTask(tasks[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", tasks[0].ID}, got.DependsOn)
+
+		// Ссылка вперёд отклоняет весь пакет
+		forward := model.NewTask("Forward", "", "default", nil)
+		forward.DependsOn = []string{BatchRef(1)}
+		_, err = repo.CreateTasks([]*model.Task{forward, model.NewTask("Later", "", "default", nil)})
+		assert.ErrorIs(t, err, ErrInvalidBatchRef)
+
+		all, err := repo.ListTasks()
+		require.NoError(t, err)
+		assert.Len(t, all, 5)
+	})
+
+	t.Run("workflow tasks", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, workflowID := range []string{"flow", "", "other", "flow"} {
+			task := model.NewTask("Task", "", "default", nil)
+			task.WorkflowID = workflowID
+			_, err := repo.CreateTask(task)
+			require.NoError(t, err)
+		}
+		require.NoError(t, repo.DeleteTask("4"))
+
+		tasks, err := repo.ListWorkflowTasks("flow")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, taskIDs(tasks))
+
+		tasks, err = repo.ListWorkflowTasks("missing")
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepo(t)
+
+		tasks, err := repo.ListTasks()
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.CreateTask(model.NewTask("Task", "", "default", nil))
+			require.NoError(t, err)
+		}
+
+		tasks, err = repo.ListTasks()
+		require.NoError(t, err)
+		assert.Len(t, tasks, 3)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+
+		task, err := repo.CreateTask(model.NewTask("Task", "", "default", nil))
+		require.NoError(t, err)
+
+		task.UpdateStatus(model.StatusCompleted)
+		task.Result = []byte(`{"ok":true}`)
+		_, err = repo.UpdateTask(task)
+		require.NoError(t, err)
+
+		got, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCompleted, got.Status)
+		assert.JSONEq(t, `{"ok":true}`, string(got.Result))
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		repo := newRepo(t)
+
+		task, err := repo.CreateTask(model.NewTask("Task", "", "default", nil))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), task.Version)
+
+		stale, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+
+		task.UpdateStatus(model.StatusCompleted)
+		_, err = repo.UpdateTask(task)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), task.Version)
+
+		// Обновление устаревшей копии отклоняется и не меняет задачу
+		stale.UpdateStatus(model.StatusFailed)
+		_, err = repo.UpdateTask(stale)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		assert.Equal(t, int64(1), stale.Version)
+
+		got, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCompleted, got.Status)
+		assert.Equal(t, int64(2), got.Version)
+
+		// Копия, прочитанная заново, обновляется
+		got.UpdateStatus(model.StatusFailed)
+		_, err = repo.UpdateTask(got)
+		require.NoError(t, err)
+
+		missing := model.NewTask("Missing", "", "default", nil)
+		missing.ID = "42"
+		_, err = repo.UpdateTask(missing)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+	})
+
+	t.Run("copies", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// Репозиторий хранит свои копии: изменения переданных и полученных задач его не затрагивают
+		task, err := repo.CreateTask(model.NewTask("Task", "", "default", []byte(`{"n":1}`)))
+		require.NoError(t, err)
+		task.Title = "Changed"
+		task.Payload[1] = 'x'
+
+		got, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Task", got.Title)
+		assert.JSONEq(t, `{"n":1}`, string(got.Payload))
+
+		got.UpdateStatus(model.StatusProcessing)
+		tasks, err := repo.ListTasks()
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, model.StatusPending, tasks[0].Status)
+
+		_, err = repo.UpdateTask(got)
+		require.NoError(t, err)
+		got.UpdateStatus(model.StatusCompleted)
+
+		got, err = repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusProcessing, got.Status)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+
+		task, err := repo.CreateTask(model.NewTask("Task", "", "default", nil))
+		require.NoError(t, err)
+
+		require.NoError(t, repo.DeleteTask(task.ID))
+
+		_, err = repo.GetTask(task.ID)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.GetTask("42")
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		_, err = repo.UpdateTask(&model.Task{ID: "42"})
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		err = repo.DeleteTask("42")
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+	})
+
+	t.Run("idempotency key", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.FindTaskByIdempotencyKey("key")
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		for _, key := range []string{"key", "other", "key", ""} {
+			task := model.NewTask("Task", "", "default", nil)
+			task.IdempotencyKey = key
+			_, err := repo.CreateTask(task)
+			require.NoError(t, err)
+		}
+
+		// Возвращается последняя задача с этим ключом
+		task, err := repo.FindTaskByIdempotencyKey("key")
+		require.NoError(t, err)
+		assert.Equal(t, "3", task.ID)
+		assert.Equal(t, "key", task.IdempotencyKey)
+
+		_, err = repo.FindTaskByIdempotencyKey("")
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+
+		// После удаления последней задачи ключ указывает на предыдущую
+		require.NoError(t, repo.DeleteTask("3"))
+		task, err = repo.FindTaskByIdempotencyKey("key")
+		require.NoError(t, err)
+		assert.Equal(t, "1", task.ID)
+
+		require.NoError(t, repo.DeleteTask("1"))
+		_, err = repo.FindTaskByIdempotencyKey("key")
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+	})
+
+	t.Run("query filters", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for i, spec := range []struct {
+			taskType string
+			status   model.TaskStatus
+		}{
+			{"email", model.StatusPending},
+			{"report", model.StatusCompleted},
+			{"email", model.StatusCompleted},
+			{"email", model.StatusFailed},
+		} {
+			task := model.NewTask("Task", "", spec.taskType, nil)
+			task.Status = spec.status
+			task.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+			_, err := repo.CreateTask(task)
+			require.NoError(t, err)
+		}
+
+		tasks, err := repo.QueryTasks(TaskQuery{Types: []string{"email"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "3", "4"}, taskIDs(tasks))
+
+		tasks, err = repo.QueryTasks(TaskQuery{
+			Statuses: []model.TaskStatus{model.StatusCompleted, model.StatusFailed},
+			Types:    []string{"email"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"3", "4"}, taskIDs(tasks))
+
+		// Границы диапазона по времени создания не включаются
+		after, before := base, base.Add(3*time.Hour)
+		tasks, err = repo.QueryTasks(TaskQuery{CreatedAfter: &after, CreatedBefore: &before})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "3"}, taskIDs(tasks))
+	})
+
+	t.Run("query order", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		for i, priority := range []int{5, 1, 9, 5} {
+			task := model.NewTask("Task", "", "default", nil)
+			task.Priority = priority
+			task.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+			_, err := repo.CreateTask(task)
+			require.NoError(t, err)
+		}
+
+		tasks, err := repo.QueryTasks(TaskQuery{Descending: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"4", "3", "2", "1"}, taskIDs(tasks))
+
+		// Задачи с одинаковым приоритетом упорядочены по ID
+		tasks, err = repo.QueryTasks(TaskQuery{Sort: SortByPriority})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2", "1", "4", "3"}, taskIDs(tasks))
+
+		tasks, err = repo.QueryTasks(TaskQuery{Sort: SortByPriority, Descending: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"3", "4", "1", "2"}, taskIDs(tasks))
+	})
+
+	t.Run("query pages", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 5; i++ {
+			task := model.NewTask("Task", "", "default", nil)
+			task.Priority = 5
+			_, err := repo.CreateTask(task)
+			require.NoError(t, err)
+		}
+
+		query := TaskQuery{Sort: SortByPriority, Descending: true, Limit: 2}
+		first, err := repo.QueryTasks(query)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"5", "4"}, taskIDs(first))
+
+		// Новая задача не сдвигает следующие страницы
+		_, err = repo.CreateTask(model.NewTask("Task", "", "default", nil))
+		require.NoError(t, err)
+
+		cursor := CursorOf(first[len(first)-1])
+		query.After = &cursor
+		second, err := repo.QueryTasks(query)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"3", "2"}, taskIDs(second))
+
+		cursor = CursorOf(second[len(second)-1])
+		query.After = &cursor
+		last, err := repo.QueryTasks(query)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, taskIDs(last))
+	})
+}
+
+func taskIDs(tasks []*model.Task) []string {
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestInMemoryTaskRepository(t *testing.T) {
+	testRepository(t, func(_ *testing.T) TaskRepositoryInterface {
+		return NewTaskRepository()
+	})
+}
+
+func openFileRepository(t *testing.T, dir string) *FileTaskRepository {
+	t.Helper()
+
+	repo, err := NewFileTaskRepository(FileRepositoryConfig{Dir: dir})
+	require.NoError(t, err)
+	return repo
+}
+
+func TestFileTaskRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) TaskRepositoryInterface {
+		repo := openFileRepository(t, t.TempDir())
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSQLiteTaskRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) TaskRepositoryInterface {
+		repo, err := NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: filepath.Join(t.TempDir(), "tasks.db")})
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSQLiteTaskRepositoryCreatesDirectory(t *testing.T) {
+	// Путь по умолчанию указывает в каталог, которого ещё нет
+	path := filepath.Join(t.TempDir(), "data", "tasks.db")
+
+	repo, err := NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: path})
+	require.NoError(t, err)
+	defer repo.Close()
+
+	_, err = repo.CreateTask(model.NewTask("Task", "", "default", nil))
+	require.NoError(t, err)
+	assert.FileExists(t, path)
+}
+
+func TestSQLiteTaskRepositoryReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	repo, err := NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: path})
+	require.NoError(t, err)
+	task, err := repo.CreateTask(model.NewTask("Task", "", "default", []byte(`{"n":1}`)))
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// Повторное открытие не должно заново применять миграции
+	repo, err = NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: path})
+	require.NoError(t, err)
+	defer repo.Close()
+
+	got, err := repo.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Task", got.Title)
+	assert.JSONEq(t, `{"n":1}`, string(got.Payload))
+}
+
+func TestFileTaskRepositoryReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+	first, err := repo.CreateTask(model.NewTask("First", "", "default", nil))
+	require.NoError(t, err)
+	second := model.NewTask("Second", "", "default", nil)
+	second.IdempotencyKey = "key"
+	second, err = repo.CreateTask(second)
+	require.NoError(t, err)
+
+	// Часть записей попадает в снапшот, остальные остаются только в WAL
+	require.NoError(t, repo.Compact())
+
+	second.UpdateStatus(model.StatusCompleted)
+	_, err = repo.UpdateTask(second)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTask(first.ID))
+	a, b := model.NewTask("A", "", "default", nil), model.NewTask("B", "", "default", nil)
+	a.WorkflowID, b.WorkflowID = "flow", "flow"
+	batch, err := repo.CreateTasks([]*model.Task{a, b})
+	require.NoError(t, err)
+
+	// Имитируем падение процесса: закрываем файлы без компактизации
+	repo.wal.Close()
+	unlockDir(repo.lock)
+
+	// Недописанная запись в конце WAL должна игнорироваться
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"create","task":{"id":"9"`)
+	require.NoError(t, err)
+	f.Close()
+
+	repo = openFileRepository(t, dir)
+	defer repo.Close()
+
+	_, err = repo.GetTask(first.ID)
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+
+	got, err := repo.GetTask(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusCompleted, got.Status)
+
+	// Индекс ключей идемпотентности восстанавливается вместе с задачами
+	got, err = repo.FindTaskByIdempotencyKey("key")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, got.ID)
+
+	// Пакет задач восстанавливается из одной записи WAL вместе с индексом процессов
+	workflow, err := repo.ListWorkflowTasks("flow")
+	require.NoError(t, err)
+	assert.Equal(t, taskIDs(batch), taskIDs(workflow))
+
+	tasks, err := repo.ListTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 3)
+
+	// Нумерация продолжается после восстановленных задач
+	next, err := repo.CreateTask(model.NewTask("Next", "", "default", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "5", next.ID)
+}
+
+func TestFileTaskRepositoryCorruptWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+	_, err := repo.CreateTask(model.NewTask("First", "", "default", nil))
+	require.NoError(t, err)
+	repo.wal.Close()
+	unlockDir(repo.lock)
+
+	// Битая запись, за которой есть другие, — это не обрыв при падении, а повреждение
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString("{\"op\":\"create\",\"task\":\n" + `{"op":"delete","id":"1"}` + "\n")
+	require.NoError(t, err)
+	f.Close()
+
+	_, err = NewFileTaskRepository(FileRepositoryConfig{Dir: dir})
+	assert.ErrorIs(t, err, ErrCorruptWAL)
+}
+
+func TestFileTaskRepositoryWriteError(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+	first, err := repo.CreateTask(model.NewTask("First", "", "default", nil))
+	require.NoError(t, err)
+
+	// Подменяем WAL файлом только для чтения: запись и откат не удаются
+	wal := repo.wal
+	repo.wal, err = os.Open(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	wal.Close()
+
+	_, err = repo.CreateTask(model.NewTask("Lost", "", "default", nil))
+	require.Error(t, err)
+	_, err = repo.GetTask("2")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+
+	// Пока WAL не восстановлен, запись отклоняется, а чтение работает
+	_, err = repo.CreateTask(model.NewTask("Refused", "", "default", nil))
+	require.Error(t, err)
+	_, err = repo.GetTask(first.ID)
+	require.NoError(t, err)
+
+	// Пакет задач тоже не сохраняется частично
+	_, err = repo.CreateTasks([]*model.Task{model.NewTask("A", "", "default", nil), model.NewTask("B", "", "default", nil)})
+	require.Error(t, err)
+	tasks, err := repo.ListTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	// Компактизация начинает новый WAL
+	require.NoError(t, repo.Compact())
+	second, err := repo.CreateTask(model.NewTask("Second", "", "default", nil))
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	repo = openFileRepository(t, dir)
+	defer repo.Close()
+
+	tasks, err = repo.ListTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	_, err = repo.GetTask(second.ID)
+	require.NoError(t, err)
+}
+
+func TestFileTaskRepositoryLock(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+
+	_, err := NewFileTaskRepository(FileRepositoryConfig{Dir: dir})
+	assert.ErrorIs(t, err, ErrRepositoryLocked)
+
+	require.NoError(t, repo.Close())
+
+	repo = openFileRepository(t, dir)
+	require.NoError(t, repo.Close())
+}
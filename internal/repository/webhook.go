@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+//go:generate mockgen -source=webhook.go -destination=mocks/webhook_mock.go -package=mocks
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepositoryInterface interface {
+	CreateWebhook(webhook *model.WebhookSubscription) (*model.WebhookSubscription, error)
+	ListWebhooks() ([]*model.WebhookSubscription, error)
+	DeleteWebhook(id string) error
+	CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error)
+	GetDelivery(id string) (*model.WebhookDelivery, error)
+	UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error)
+	ListDeliveries(taskID string) ([]*model.WebhookDelivery, error)
+	// ListPendingDeliveries returns the deliveries that are still to be sent
+	ListPendingDeliveries() ([]*model.WebhookDelivery, error)
+	// PruneDeliveries removes finished deliveries created before the given
+	// time and, if keep is positive, all but the keep most recent finished
+	// ones. It returns how many were removed.
+	PruneDeliveries(before time.Time, keep int) (int, error)
+}
+
+type InMemoryWebhookRepository struct {
+	webhooks       map[string]*model.WebhookSubscription
+	deliveries     map[string]*model.WebhookDelivery
+	mu             sync.RWMutex
+	nextWebhookID  int64
+	nextDeliveryID int64
+}
+
+func NewWebhookRepository() WebhookRepositoryInterface {
+	return &InMemoryWebhookRepository{
+		webhooks:       make(map[string]*model.WebhookSubscription),
+		deliveries:     make(map[string]*model.WebhookDelivery),
+		nextWebhookID:  1,
+		nextDeliveryID: 1,
+	}
+}
+
+func (r *InMemoryWebhookRepository) CreateWebhook(webhook *model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook.ID = strconv.FormatInt(r.nextWebhookID, 10)
+	r.nextWebhookID++
+	r.webhooks[webhook.ID] = webhook.Clone()
+	return webhook, nil
+}
+
+func (r *InMemoryWebhookRepository) ListWebhooks() ([]*model.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]*model.WebhookSubscription, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, webhook.Clone())
+	}
+	return webhooks, nil
+}
+
+func (r *InMemoryWebhookRepository) DeleteWebhook(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.ID = strconv.FormatInt(r.nextDeliveryID, 10)
+	r.nextDeliveryID++
+	r.deliveries[delivery.ID] = delivery.Clone()
+	return delivery, nil
+}
+
+func (r *InMemoryWebhookRepository) GetDelivery(id string) (*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery.Clone(), nil
+}
+
+func (r *InMemoryWebhookRepository) UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deliveries[delivery.ID]; !exists {
+		return nil, ErrDeliveryNotFound
+	}
+
+	r.deliveries[delivery.ID] = delivery.Clone()
+	return delivery, nil
+}
+
+// ListDeliveries returns the deliveries of a task's events in the order they were created
+func (r *InMemoryWebhookRepository) ListDeliveries(taskID string) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return filterDeliveries(r.deliveries, func(delivery *model.WebhookDelivery) bool {
+		return delivery.TaskID == taskID
+	}), nil
+}
+
+func (r *InMemoryWebhookRepository) ListPendingDeliveries() ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return filterDeliveries(r.deliveries, func(delivery *model.WebhookDelivery) bool {
+		return delivery.Status == model.DeliveryPending
+	}), nil
+}
+
+func (r *InMemoryWebhookRepository) PruneDeliveries(before time.Time, keep int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := prunableDeliveries(r.deliveries, before, keep)
+	for _, id := range ids {
+		delete(r.deliveries, id)
+	}
+	return len(ids), nil
+}
+
+// filterDeliveries returns copies of the deliveries matching keep in the
+// order they were created
+func filterDeliveries(deliveries map[string]*model.WebhookDelivery, keep func(*model.WebhookDelivery) bool) []*model.WebhookDelivery {
+	matched := make([]*model.WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		if keep(delivery) {
+			matched = append(matched, delivery.Clone())
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareIDs(matched[i].ID, matched[j].ID) < 0
+	})
+	return matched
+}
+
+// prunableDeliveries returns the IDs of the deliveries PruneDeliveries removes
+func prunableDeliveries(deliveries map[string]*model.WebhookDelivery, before time.Time, keep int) []string {
+	finished := make([]*model.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		if delivery.Status != model.DeliveryPending {
+			finished = append(finished, delivery)
+		}
+	}
+	// Most recent first
+	sort.Slice(finished, func(i, j int) bool {
+		return compareIDs(finished[i].ID, finished[j].ID) > 0
+	})
+
+	var ids []string
+	for i, delivery := range finished {
+		if delivery.CreatedAt.Before(before) || (keep > 0 && i >= keep) {
+			ids = append(ids, delivery.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// testWebhookRepository проверяет поведение, общее для всех реализаций WebhookRepositoryInterface
+func testWebhookRepository(t *testing.T, newRepo func(t *testing.T) WebhookRepositoryInterface) {
+	t.Run("webhooks", func(t *testing.T) {
+		repo := newRepo(t)
+
+		webhook, err := repo.CreateWebhook(&model.WebhookSubscription{URL: "http://example.com", CreatedAt: time.Now()})
+		require.NoError(t, err)
+		assert.Equal(t, "1", webhook.ID)
+
+		webhooks, err := repo.ListWebhooks()
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "http://example.com", webhooks[0].URL)
+
+		require.NoError(t, repo.DeleteWebhook(webhook.ID))
+		assert.ErrorIs(t, repo.DeleteWebhook(webhook.ID), ErrWebhookNotFound)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for _, taskID := range []string{"1", "2", "1"} {
+			_, err := repo.CreateDelivery(&model.WebhookDelivery{TaskID: taskID, Status: model.DeliveryPending})
+			require.NoError(t, err)
+		}
+
+		delivery, err := repo.GetDelivery("3")
+		require.NoError(t, err)
+		delivery.Status = model.DeliveryDelivered
+		_, err = repo.UpdateDelivery(delivery)
+		require.NoError(t, err)
+
+		// Доставки задачи возвращаются в порядке создания
+		deliveries, err := repo.ListDeliveries("1")
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, "1", deliveries[0].ID)
+		assert.Equal(t, model.DeliveryDelivered, deliveries[1].Status)
+
+		// Отправленная доставка больше не ожидает отправки
+		pending, err := repo.ListPendingDeliveries()
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		assert.Equal(t, "1", pending[0].ID)
+		assert.Equal(t, "2", pending[1].ID)
+
+		_, err = repo.GetDelivery("42")
+		assert.ErrorIs(t, err, ErrDeliveryNotFound)
+		_, err = repo.UpdateDelivery(&model.WebhookDelivery{ID: "42"})
+		assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	})
+
+	t.Run("prune deliveries", func(t *testing.T) {
+		repo := newRepo(t)
+		now := time.Now()
+
+		for i, status := range []model.DeliveryStatus{
+			model.DeliveryDelivered, model.DeliveryPending, model.DeliveryFailed,
+			model.DeliveryDelivered, model.DeliveryDelivered,
+		} {
+			_, err := repo.CreateDelivery(&model.WebhookDelivery{
+				TaskID:    "1",
+				Status:    status,
+				CreatedAt: now.Add(time.Duration(i-5) * time.Hour),
+			})
+			require.NoError(t, err)
+		}
+
+		// Старше двух с половиной часов: первая и третья доставки, ожидающая остаётся
+		removed, err := repo.PruneDeliveries(now.Add(-150*time.Minute), 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, removed)
+
+		// Из завершённых остаётся только последняя
+		removed, err = repo.PruneDeliveries(time.Time{}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, removed)
+
+		deliveries, err := repo.ListDeliveries("1")
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, "2", deliveries[0].ID)
+		assert.Equal(t, "5", deliveries[1].ID)
+	})
+}
+
+func TestInMemoryWebhookRepository(t *testing.T) {
+	testWebhookRepository(t, func(_ *testing.T) WebhookRepositoryInterface {
+		return NewWebhookRepository()
+	})
+}
+
+func TestFileWebhookRepository(t *testing.T) {
+	testWebhookRepository(t, func(t *testing.T) WebhookRepositoryInterface {
+		repo := openFileRepository(t, t.TempDir())
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestSQLiteWebhookRepository(t *testing.T) {
+	testWebhookRepository(t, func(t *testing.T) WebhookRepositoryInterface {
+		repo, err := NewSQLiteTaskRepository(SQLiteRepositoryConfig{Path: filepath.Join(t.TempDir(), "tasks.db")})
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestFileWebhookRepositoryReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	repo := openFileRepository(t, dir)
+	webhook, err := repo.CreateWebhook(&model.WebhookSubscription{URL: "http://example.com", Events: []string{"task.completed"}})
+	require.NoError(t, err)
+	_, err = repo.CreateDelivery(&model.WebhookDelivery{TaskID: "1", Status: model.DeliveryDelivered})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Compact())
+	pending, err := repo.CreateDelivery(&model.WebhookDelivery{TaskID: "1", Status: model.DeliveryPending})
+	require.NoError(t, err)
+
+	// Имитируем падение процесса: ожидающая доставка есть только в WAL
+	repo.wal.Close()
+	unlockDir(repo.lock)
+
+	repo = openFileRepository(t, dir)
+	defer repo.Close()
+
+	webhooks, err := repo.ListWebhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, webhook.ID, webhooks[0].ID)
+	assert.Equal(t, []string{"task.completed"}, webhooks[0].Events)
+
+	deliveries, err := repo.ListPendingDeliveries()
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, pending.ID, deliveries[0].ID)
+
+	next, err := repo.CreateDelivery(&model.WebhookDelivery{TaskID: "1", Status: model.DeliveryPending})
+	require.NoError(t, err)
+	assert.Equal(t, "3", next.ID)
+}
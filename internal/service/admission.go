@@ -0,0 +1,197 @@
+package service
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// QueueFullPolicy decides what happens to a new task while the queue is full
+type QueueFullPolicy string
+
+const (
+	// QueueFullReject turns the task away, asking the client to retry later
+	QueueFullReject QueueFullPolicy = "reject"
+	// QueueFullOverflow holds the task in a bounded overflow buffer and rejects
+	// it only once the buffer is full as well
+	QueueFullOverflow QueueFullPolicy = "overflow"
+)
+
+// Bounds of the retry delay suggested to rejected clients
+const (
+	minRetryAfter = time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// The throughput is measured over the task starts of the last
+// throughputWindow, remembering at most throughputSamples of them
+const (
+	throughputWindow  = 30 * time.Second
+	throughputSamples = 128
+)
+
+var ErrQueueFull = errors.New("task queue is full")
+
+// throughputMeter remembers when the most recent tasks left the queue
+type throughputMeter struct {
+	times []time.Time
+	next  int
+}
+
+func newThroughputMeter() throughputMeter {
+	return throughputMeter{times: make([]time.Time, 0, throughputSamples)}
+}
+
+func (m *throughputMeter) record(t time.Time) {
+	if len(m.times) < cap(m.times) {
+		m.times = append(m.times, t)
+		return
+	}
+	m.times[m.next] = t
+	m.next = (m.next + 1) % len(m.times)
+}
+
+// rate returns the tasks leaving the queue per second, measured from the
+// oldest remembered start within the window until now, or 0 if no task has
+// left the queue within the window. Starts before an idle period do not
+// count, so they do not drag the rate down once the workers are busy again.
+func (m *throughputMeter) rate(now time.Time) float64 {
+	cutoff := now.Add(-throughputWindow)
+
+	var oldest time.Time
+	n := 0
+	for _, t := range m.times {
+		if t.After(cutoff) {
+			n++
+			if oldest.IsZero() || t.Before(oldest) {
+				oldest = t
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+
+	elapsed := now.Sub(oldest).Seconds()
+	if elapsed <= 0 {
+		return math.Inf(1)
+	}
+	return float64(n) / elapsed
+}
+
+// Reserve admits n new tasks if the queue and its overflow buffer have room
+// for them, counting tasks admitted but not pushed yet. Each admitted task
+// has to be pushed with PushReserved or handed back with Unreserve.
+func (q *taskScheduler) Reserve(n int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.capacity > 0 && q.depth()+n > q.capacity+q.overflowCapacity {
+		return false
+	}
+	q.reserved += n
+	return true
+}
+
+// Unreserve hands back places taken by Reserve for tasks that were not created
+func (q *taskScheduler) Unreserve(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reserved = max(q.reserved-n, 0)
+	signal(q.space)
+}
+
+// PushReserved adds a task admitted by Reserve. It waits in the overflow
+// buffer while the queue is full. PushReserved reports whether the task went
+// straight into the queue.
+func (q *taskScheduler) PushReserved(task *model.Task) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reserved = max(q.reserved-1, 0)
+	q.seq++
+	item := &queuedTask{task: task.Clone(), score: q.score(task), seq: q.seq, queuedAt: q.now()}
+
+	if q.capacity > 0 && len(q.items) >= q.capacity {
+		item.overflowed = true
+		q.overflow = append(q.overflow, item)
+		return false
+	}
+
+	heap.Push(&q.items, item)
+	signal(q.available)
+	return true
+}
+
+// promoteOverflow moves the oldest overflow task into the queue once it has
+// room. The task keeps the score it was given on admission, so the time it
+// spent in the buffer counts towards aging.
+func (q *taskScheduler) promoteOverflow() {
+	if len(q.overflow) == 0 || (q.capacity > 0 && len(q.items) >= q.capacity) {
+		return
+	}
+
+	item := q.overflow[0]
+	q.overflow[0] = nil
+	q.overflow = q.overflow[1:]
+	item.overflowed = false
+	heap.Push(&q.items, item)
+	signal(q.available)
+}
+
+// depth counts the tasks waiting in the queue or its overflow buffer and the
+// places reserved for tasks about to join them
+func (q *taskScheduler) depth() int {
+	return len(q.items) + len(q.overflow) + q.reserved
+}
+
+// RetryAfter estimates how long the workers need to work off the waiting
+// tasks at the rate they have recently taken tasks from the queue. Without
+// a recent rate it suggests one measuring window.
+func (q *taskScheduler) RetryAfter() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	rate := q.pops.rate(q.now())
+	if rate == 0 {
+		return throughputWindow
+	}
+
+	wait := time.Duration(float64(q.depth()) / rate * float64(time.Second))
+	return min(max(wait, minRetryAfter), maxRetryAfter)
+}
+
+// RetryAfter suggests how long a client whose task was turned away with
+// ErrQueueFull should wait before trying again
+func (s *TaskService) RetryAfter() time.Duration {
+	return s.queue.RetryAfter()
+}
+
+// admit reserves queue places for n tasks that are ready to run
+func (s *TaskService) admit(n int) error {
+	if n == 0 || s.queue.Reserve(n) {
+		return nil
+	}
+
+	s.logger.Warn("Task queue is full, rejecting new tasks",
+		zap.Int("tasks", n),
+		zap.Int("queued", s.queue.Len()))
+	return ErrQueueFull
+}
+
+// readyTasks counts the tasks that go to the queue as soon as they are created
+func readyTasks(tasks []*model.Task) int {
+	n := 0
+	for _, task := range tasks {
+		if task != nil && task.Status == model.StatusPending {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestSchedulerReserve(t *testing.T) {
+	q := newTaskScheduler(QueueConfig{Capacity: 2}, make(chan struct{}))
+
+	require.True(t, q.Reserve(2))
+	assert.False(t, q.Reserve(1))
+	// Зарезервированные места недоступны и для внутренних отправителей
+	assert.False(t, q.TryPush(newQueuedTask("internal", 5)))
+
+	q.Unreserve(1)
+	require.True(t, q.PushReserved(newQueuedTask("1", 5)))
+	require.True(t, q.Reserve(1))
+	require.True(t, q.PushReserved(newQueuedTask("2", 5)))
+	assert.False(t, q.Reserve(1))
+
+	assert.Equal(t, []string{"1"}, popIDs(t, q, 1))
+	assert.True(t, q.Reserve(1))
+}
+
+func TestSchedulerOverflow(t *testing.T) {
+	config := QueueConfig{Capacity: 1, FullPolicy: QueueFullOverflow, OverflowCapacity: 2}
+	q := newTaskScheduler(config, make(chan struct{}))
+
+	for _, id := range []string{"1", "2", "3"} {
+		require.True(t, q.Reserve(1))
+		q.PushReserved(newQueuedTask(id, 5))
+	}
+	assert.False(t, q.Reserve(1))
+	assert.Equal(t, 3, q.Len())
+
+	// Задачи переходят из буфера в очередь по мере освобождения места,
+	// и высокий приоритет не обгоняет задачи, принятые раньше
+	_, removed := q.Remove("3")
+	require.True(t, removed)
+	require.True(t, q.Reserve(1))
+	assert.False(t, q.PushReserved(newQueuedTask("4", 9)))
+
+	// Задача, вынутая из буфера, возвращается на своё место в буфере
+	item, removed := q.Remove("2")
+	require.True(t, removed)
+	q.Restore(item, item.task)
+	assert.Len(t, q.items, 1)
+	assert.Equal(t, 3, q.Len())
+
+	assert.Equal(t, []string{"1", "2", "4"}, popIDs(t, q, 3))
+	assert.Equal(t, 0, q.Len())
+
+	// Без политики overflow буфер не используется
+	q = newTaskScheduler(QueueConfig{Capacity: 1, OverflowCapacity: 2}, make(chan struct{}))
+	require.True(t, q.Reserve(1))
+	assert.False(t, q.Reserve(1))
+}
+
+func TestSchedulerRetryAfter(t *testing.T) {
+	now := time.Now()
+	q := newTaskScheduler(QueueConfig{Capacity: 10}, make(chan struct{}))
+	q.now = func() time.Time { return now }
+
+	// Пока ни одна задача не взята в работу, пропускная способность неизвестна
+	assert.Equal(t, throughputWindow, q.RetryAfter())
+
+	for i := 0; i < 10; i++ {
+		require.True(t, q.TryPush(newQueuedTask("task", 5)))
+	}
+	// Воркеры берут по задаче раз в две секунды
+	for i := 0; i < 4; i++ {
+		now = now.Add(2 * time.Second)
+		popIDs(t, q, 1)
+	}
+
+	// 6 задач ждут, а с первой взятой задачи прошло 6 секунд и взято ещё 3
+	assert.Equal(t, 9*time.Second, q.RetryAfter())
+
+	// После простоя старые замеры не учитываются
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, throughputWindow, q.RetryAfter())
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		popIDs(t, q, 1)
+	}
+	// 3 задачи ждут, а за 2 секунды после первой взято 3
+	assert.Equal(t, 2*time.Second, q.RetryAfter())
+
+	popIDs(t, q, 3)
+	assert.Equal(t, minRetryAfter, q.RetryAfter())
+}
+
+func TestQueueAdmission(t *testing.T) {
+	repo := repository.NewTaskRepository()
+	config := DefaultConfig()
+	config.Queue.Capacity = 1
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+	newRequest := func() dto.CreateTaskRequest {
+		return dto.CreateTaskRequest{Title: "Task", Description: "Description"}
+	}
+
+	// Занимаем все воркеры и единственное место в очереди
+	for i := 0; i < service.WorkerCount(); i++ {
+		task, err := service.CreateTask(context.Background(), newRequest())
+		require.NoError(t, err)
+		waitForStatus(t, repo, task.ID, model.StatusProcessing)
+	}
+	_, err := service.CreateTask(context.Background(), newRequest())
+	require.NoError(t, err)
+
+	t.Run("task", func(t *testing.T) {
+		_, err := service.CreateTask(context.Background(), newRequest())
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		tasks, err := repo.ListTasks()
+		require.NoError(t, err)
+		assert.Len(t, tasks, service.WorkerCount()+1)
+		assert.GreaterOrEqual(t, service.RetryAfter(), minRetryAfter)
+	})
+
+	t.Run("scheduled", func(t *testing.T) {
+		// Отложенные задачи не занимают место в очереди при создании
+		req := newRequest()
+		req.Delay = "1h"
+		task, err := service.CreateTask(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusScheduled, task.Status)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		scheduled := newRequest()
+		scheduled.Delay = "1h"
+		reqs := []dto.CreateTaskRequest{scheduled, newRequest()}
+
+		_, err := service.CreateTaskBatch(context.Background(), BatchAllOrNothing, reqs)
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		result, err := service.CreateTaskBatch(context.Background(), BatchBestEffort, reqs)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Created)
+		assert.NotNil(t, result.Items[0].Task)
+		assert.ErrorIs(t, result.Items[1].Err, ErrQueueFull)
+	})
+
+	t.Run("workflow", func(t *testing.T) {
+		dependent := newRequest()
+		dependent.DependsOn = []string{"a"}
+		_, err := service.CreateWorkflow(context.Background(), dto.CreateWorkflowRequest{
+			Tasks: []dto.WorkflowTaskRequest{
+				{Key: "a", CreateTaskRequest: newRequest()},
+				{Key: "b", CreateTaskRequest: dependent},
+			},
+		})
+		assert.ErrorIs(t, err, ErrQueueFull)
+	})
+
+	t.Run("redrive", func(t *testing.T) {
+		task := createStoredTask(t, repo, model.StatusDeadLetter)
+
+		_, err := service.RedriveTask(context.Background(), task.ID)
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		// Отклонённая задача остаётся в очереди недоставленных
+		stored, err := repo.GetTask(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusDeadLetter, stored.Status)
+	})
+
+	t.Run("due", func(t *testing.T) {
+		req := newRequest()
+		req.Delay = "10ms"
+		task, err := service.CreateTask(context.Background(), req)
+		require.NoError(t, err)
+
+		// Наступившая задача не блокирует очередь отложенных, а ждёт в ней места
+		waitForStatus(t, repo, task.ID, model.StatusPending)
+		require.Eventually(t, func() bool {
+			service.delayed.mu.Lock()
+			defer service.delayed.mu.Unlock()
+			for _, item := range service.delayed.items {
+				if item.id == task.ID && item.dueAt.After(time.Now()) {
+					return true
+				}
+			}
+			return false
+		}, time.Second, 5*time.Millisecond)
+	})
+}
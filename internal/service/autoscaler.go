@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+// maxScalingDecisions is the number of recent decisions kept in the status
+const maxScalingDecisions = 100
+
+// Reasons of scaling decisions
+const (
+	ScaleReasonMinWorkers = "min_workers"
+	ScaleReasonMaxWorkers = "max_workers"
+	ScaleReasonQueueDepth = "queue_depth"
+	ScaleReasonLatency    = "latency"
+	ScaleReasonIdle       = "idle"
+)
+
+// AutoscalerConfig holds configuration of the worker pool autoscaler
+type AutoscalerConfig struct {
+	Enabled    bool
+	MinWorkers int
+	MaxWorkers int
+	// Interval is how often the load is checked
+	Interval time.Duration
+	// ScaleUpQueueDepth is the number of waiting tasks that grows the pool.
+	// 0 disables this trigger.
+	ScaleUpQueueDepth int
+	// ScaleUpLatency is how long a task may wait for a worker before the pool
+	// grows. 0 disables this trigger.
+	ScaleUpLatency time.Duration
+	// ScaleUpCooldown is the least time between a resize and growing the pool
+	ScaleUpCooldown time.Duration
+	// ScaleDownCooldown is how long the queue has to stay empty, and the pool
+	// unchanged, before idle workers are retired
+	ScaleDownCooldown time.Duration
+}
+
+// ScalableWorkerPool is the worker pool the autoscaler resizes and the load
+// it watches
+type ScalableWorkerPool interface {
+	WorkerCount() int
+	BusyWorkers() int
+	QueueDepth() int
+	OldestTaskWait() time.Duration
+	ResizeWorkers(count int) error
+}
+
+// ErrAutoscalerEnabled is returned for a manual resize while the autoscaler
+// sizes the pool, since it would undo the resize on its next check
+var ErrAutoscalerEnabled = errors.New("worker pool is sized by the autoscaler")
+
+type AutoscalerInterface interface {
+	// Enabled reports whether the autoscaler sizes the worker pool
+	Enabled() bool
+	Status() *model.AutoscalerStatus
+	Shutdown(ctx context.Context) error
+}
+
+// poolLoad is the state of the worker pool at one check
+type poolLoad struct {
+	workers    int
+	busy       int
+	queueDepth int
+	oldestWait time.Duration
+}
+
+// Autoscaler grows the worker pool when tasks pile up or wait too long and
+// retires idle workers once the queue has stayed empty for a while. Cooldowns
+// keep it from resizing the pool back and forth under spiky load.
+type Autoscaler struct {
+	pool   ScalableWorkerPool
+	logger *logger.Logger
+	config AutoscalerConfig
+	now    func() time.Time
+
+	mu sync.Mutex
+	// lastScaledAt is when the pool was last resized
+	lastScaledAt time.Time
+	// lastPressureAt is when tasks were last seen waiting
+	lastPressureAt time.Time
+	status         model.AutoscalerStatus
+
+	wg           sync.WaitGroup
+	shutdownChan chan struct{}
+}
+
+// NewAutoscaler starts checking the load of the pool if the autoscaler is enabled
+func NewAutoscaler(pool ScalableWorkerPool, logger *logger.Logger, config AutoscalerConfig) *Autoscaler {
+	autoscaler := &Autoscaler{
+		pool:           pool,
+		logger:         logger,
+		config:         config,
+		now:            time.Now,
+		lastPressureAt: time.Now(),
+		status: model.AutoscalerStatus{
+			Enabled:    config.Enabled,
+			MinWorkers: config.MinWorkers,
+			MaxWorkers: config.MaxWorkers,
+		},
+		shutdownChan: make(chan struct{}),
+	}
+
+	if config.Enabled && config.Interval > 0 {
+		autoscaler.wg.Add(1)
+		go autoscaler.run()
+	}
+
+	return autoscaler
+}
+
+func (a *Autoscaler) run() {
+	defer a.wg.Done()
+
+	a.logger.Info("Autoscaler started",
+		zap.Int("min_workers", a.config.MinWorkers),
+		zap.Int("max_workers", a.config.MaxWorkers))
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.shutdownChan:
+			return
+		case <-ticker.C:
+			a.evaluate()
+		}
+	}
+}
+
+// evaluate checks the load of the pool and resizes it if needed
+func (a *Autoscaler) evaluate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	load := poolLoad{
+		workers:    a.pool.WorkerCount(),
+		busy:       a.pool.BusyWorkers(),
+		queueDepth: a.pool.QueueDepth(),
+		oldestWait: a.pool.OldestTaskWait(),
+	}
+	a.status.Evaluations++
+	if load.queueDepth > 0 {
+		a.lastPressureAt = now
+	}
+
+	target, reason := a.target(load, now)
+	if target == load.workers {
+		return
+	}
+
+	if err := a.pool.ResizeWorkers(target); err != nil {
+		if !errors.Is(err, ErrServiceStopped) {
+			a.logger.Error("Failed to scale worker pool", err,
+				zap.Int("from", load.workers),
+				zap.Int("to", target))
+		}
+		return
+	}
+
+	a.lastScaledAt = now
+	if target > load.workers {
+		a.status.ScaleUps++
+	} else {
+		a.status.ScaleDowns++
+	}
+
+	decision := model.ScalingDecision{
+		At:          now,
+		From:        load.workers,
+		To:          target,
+		Reason:      reason,
+		QueueDepth:  load.queueDepth,
+		OldestWait:  load.oldestWait,
+		BusyWorkers: load.busy,
+	}
+	if len(a.status.Decisions) == maxScalingDecisions {
+		a.status.Decisions = append(a.status.Decisions[:0], a.status.Decisions[1:]...)
+	}
+	a.status.Decisions = append(a.status.Decisions, decision)
+
+	a.logger.Info("Worker pool scaled",
+		zap.Int("from", load.workers),
+		zap.Int("to", target),
+		zap.String("reason", reason),
+		zap.Int("queue_depth", load.queueDepth),
+		zap.Duration("oldest_wait", load.oldestWait),
+		zap.Int("busy_workers", load.busy))
+}
+
+// target returns the pool size the load calls for and why. The pool grows
+// to a worker for every running and waiting task, bounded by the maximum,
+// and shrinks to the busy workers, bounded by the minimum.
+func (a *Autoscaler) target(load poolLoad, now time.Time) (int, string) {
+	minWorkers := max(a.config.MinWorkers, 1)
+	maxWorkers := max(a.config.MaxWorkers, minWorkers)
+
+	switch {
+	case load.workers < minWorkers:
+		return minWorkers, ScaleReasonMinWorkers
+	case load.workers > maxWorkers:
+		return maxWorkers, ScaleReasonMaxWorkers
+	}
+
+	if load.queueDepth > 0 {
+		var reason string
+		switch {
+		case a.config.ScaleUpQueueDepth > 0 && load.queueDepth >= a.config.ScaleUpQueueDepth:
+			reason = ScaleReasonQueueDepth
+		case a.config.ScaleUpLatency > 0 && load.oldestWait >= a.config.ScaleUpLatency:
+			reason = ScaleReasonLatency
+		default:
+			return load.workers, ""
+		}
+
+		if now.Sub(a.lastScaledAt) < a.config.ScaleUpCooldown {
+			return load.workers, ""
+		}
+		return min(max(load.workers+1, load.busy+load.queueDepth), maxWorkers), reason
+	}
+
+	if load.busy < load.workers &&
+		now.Sub(a.lastPressureAt) >= a.config.ScaleDownCooldown &&
+		now.Sub(a.lastScaledAt) >= a.config.ScaleDownCooldown {
+		return max(load.busy, minWorkers), ScaleReasonIdle
+	}
+	return load.workers, ""
+}
+
+func (a *Autoscaler) Enabled() bool {
+	return a.config.Enabled
+}
+
+// Status returns the current load of the pool and the recent decisions
+func (a *Autoscaler) Status() *model.AutoscalerStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := a.status
+	status.Workers = a.pool.WorkerCount()
+	status.BusyWorkers = a.pool.BusyWorkers()
+	status.QueueDepth = a.pool.QueueDepth()
+	status.OldestWait = a.pool.OldestTaskWait()
+	status.Decisions = append([]model.ScalingDecision(nil), a.status.Decisions...)
+	return &status
+}
+
+// Shutdown stops the autoscaler
+func (a *Autoscaler) Shutdown(ctx context.Context) error {
+	a.logger.Info("Shutting down autoscaler")
+	close(a.shutdownChan)
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "ctx done")
+	case <-done:
+		return nil
+	}
+}
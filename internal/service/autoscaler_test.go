@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// fakeWorkerPool reports a load set by the test
+type fakeWorkerPool struct {
+	workers    int
+	busy       int
+	queueDepth int
+	oldestWait time.Duration
+}
+
+func (p *fakeWorkerPool) WorkerCount() int              { return p.workers }
+func (p *fakeWorkerPool) BusyWorkers() int              { return p.busy }
+func (p *fakeWorkerPool) QueueDepth() int               { return p.queueDepth }
+func (p *fakeWorkerPool) OldestTaskWait() time.Duration { return p.oldestWait }
+
+func (p *fakeWorkerPool) ResizeWorkers(count int) error {
+	p.workers = count
+	return nil
+}
+
+func TestAutoscaler(t *testing.T) {
+	config := AutoscalerConfig{
+		MinWorkers:        2,
+		MaxWorkers:        10,
+		ScaleUpQueueDepth: 5,
+		ScaleUpLatency:    time.Second,
+		ScaleUpCooldown:   10 * time.Second,
+		ScaleDownCooldown: time.Minute,
+	}
+	pool := &fakeWorkerPool{workers: 1}
+	autoscaler := NewAutoscaler(pool, setupTestLogger(), config)
+	now := time.Now()
+	autoscaler.now = func() time.Time { return now }
+	assert.False(t, autoscaler.Enabled())
+
+	// Пул меньше минимального сразу дорастает до минимума
+	autoscaler.evaluate()
+	assert.Equal(t, 2, pool.workers)
+
+	// Очередь короче порога и задачи ждут недолго — пул не меняется
+	now = now.Add(time.Minute)
+	pool.busy, pool.queueDepth, pool.oldestWait = 2, 3, 100*time.Millisecond
+	autoscaler.evaluate()
+	assert.Equal(t, 2, pool.workers)
+
+	// Долгое ожидание добавляет воркер на каждую ждущую задачу
+	pool.oldestWait = 2 * time.Second
+	autoscaler.evaluate()
+	assert.Equal(t, 5, pool.workers)
+
+	// Во время паузы после изменения пул не растёт
+	now = now.Add(5 * time.Second)
+	pool.busy, pool.queueDepth = 5, 20
+	autoscaler.evaluate()
+	assert.Equal(t, 5, pool.workers)
+
+	// После паузы длинная очередь увеличивает пул до максимума
+	now = now.Add(5 * time.Second)
+	autoscaler.evaluate()
+	assert.Equal(t, 10, pool.workers)
+
+	// Пул уменьшается, только когда очередь пуста дольше паузы
+	pool.busy, pool.queueDepth, pool.oldestWait = 3, 0, 0
+	now = now.Add(30 * time.Second)
+	autoscaler.evaluate()
+	assert.Equal(t, 10, pool.workers)
+
+	now = now.Add(time.Minute)
+	autoscaler.evaluate()
+	assert.Equal(t, 3, pool.workers)
+
+	// Свободные воркеры останавливаются не ниже минимума
+	now = now.Add(2 * time.Minute)
+	pool.busy = 0
+	autoscaler.evaluate()
+	assert.Equal(t, 2, pool.workers)
+
+	status := autoscaler.Status()
+	assert.Equal(t, 8, status.Evaluations)
+	assert.Equal(t, 3, status.ScaleUps)
+	assert.Equal(t, 2, status.ScaleDowns)
+	assert.Equal(t, 2, status.Workers)
+
+	reasons := make([]string, len(status.Decisions))
+	for i, decision := range status.Decisions {
+		reasons[i] = decision.Reason
+	}
+	assert.Equal(t, []string{
+		ScaleReasonMinWorkers, ScaleReasonLatency, ScaleReasonQueueDepth, ScaleReasonIdle, ScaleReasonIdle,
+	}, reasons)
+	assert.Equal(t, 5, status.Decisions[2].From)
+	assert.Equal(t, 10, status.Decisions[2].To)
+	assert.Equal(t, 20, status.Decisions[2].QueueDepth)
+}
+
+func TestAutoscalerScalesService(t *testing.T) {
+	service, repo := newBlockingService(t)
+	service.SetWorkerCount(1)
+
+	autoscaler := NewAutoscaler(service, setupTestLogger(), AutoscalerConfig{
+		Enabled:           true,
+		MinWorkers:        1,
+		MaxWorkers:        4,
+		Interval:          10 * time.Millisecond,
+		ScaleUpQueueDepth: 1,
+		ScaleDownCooldown: time.Hour,
+	})
+	t.Cleanup(func() { autoscaler.Shutdown(context.Background()) })
+	assert.True(t, autoscaler.Enabled())
+
+	// Ждущие задачи получают новых воркеров
+	var tasks []*model.Task
+	for i := 0; i < 4; i++ {
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Busy", Description: "Description"})
+		require.NoError(t, err)
+		tasks = append(tasks, task)
+	}
+	for _, task := range tasks {
+		waitForStatus(t, repo, task.ID, model.StatusProcessing)
+	}
+
+	assert.Equal(t, 4, service.WorkerCount())
+	status := autoscaler.Status()
+	assert.Equal(t, 4, status.BusyWorkers)
+	assert.Positive(t, status.ScaleUps)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// testServiceBackend проверяет, что сервис ведёт себя одинаково поверх любого хранилища
+func testServiceBackend(t *testing.T, newRepo func(t *testing.T) repository.TaskRepositoryInterface) {
+	t.Run("retry and redrive", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var succeed atomic.Bool
+		config := DefaultConfig()
+		config.Executors = map[string]TaskExecutor{
+			DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+				if succeed.Load() {
+					return TextResult("done"), nil
+				}
+				return nil, errors.New("temporary failure")
+			}),
+		}
+		service := NewTaskService(repo, setupTestLogger(), config)
+		t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+			Title:       "Task",
+			Description: "Description",
+			MaxAttempts: 2,
+			Backoff:     &dto.BackoffPolicy{Initial: "10ms"},
+		})
+		require.NoError(t, err)
+
+		task = waitForStatus(t, repo, task.ID, model.StatusDeadLetter)
+		assert.Equal(t, 2, task.Attempts)
+
+		succeed.Store(true)
+		_, err = service.RedriveTask(context.Background(), task.ID)
+		require.NoError(t, err)
+		task = waitForStatus(t, repo, task.ID, model.StatusCompleted)
+		assert.JSONEq(t, string(TextResult("done")), string(task.Result))
+	})
+
+	t.Run("cancel and edit", func(t *testing.T) {
+		repo := newRepo(t)
+		service := newInstantService(t, repo, DefaultConfig())
+
+		later, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Later", Description: "Description", Delay: "1h"})
+		require.NoError(t, err)
+		dependent, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Dependent", Description: "Description", DependsOn: []string{later.ID}})
+		require.NoError(t, err)
+
+		// Правка и отмена работают с версией задачи из хранилища
+		title := "Edited"
+		edited, err := service.EditTask(later.ID, dto.EditTaskRequest{Title: &title})
+		require.NoError(t, err)
+		assert.Equal(t, "Edited", edited.Title)
+
+		_, err = service.CancelTask(context.Background(), later.ID, "")
+		require.NoError(t, err)
+
+		task := waitForStatus(t, repo, dependent.ID, model.StatusFailed)
+		assert.Equal(t, model.FailureReasonDependencyFailed, task.FailureReason)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		repo := newRepo(t)
+		service := newInstantService(t, repo, DefaultConfig())
+
+		result, err := service.CreateTaskBatch(context.Background(), BatchAllOrNothing, []dto.CreateTaskRequest{
+			{Title: "First", Description: "Description"},
+			{Title: "Second", Description: "Description"},
+		})
+		require.NoError(t, err)
+		for _, item := range result.Items {
+			require.NotNil(t, item.Task)
+			waitForStatus(t, repo, item.Task.ID, model.StatusCompleted)
+		}
+	})
+
+	t.Run("workflow", func(t *testing.T) {
+		repo := newRepo(t)
+		service := newInstantService(t, repo, DefaultConfig())
+
+		workflow, err := service.CreateWorkflow(context.Background(), dto.CreateWorkflowRequest{
+			Tasks: []dto.WorkflowTaskRequest{
+				{Key: "load", CreateTaskRequest: dto.CreateTaskRequest{Title: "Load", Description: "Description", DependsOn: []string{"extract"}}},
+				{Key: "extract", CreateTaskRequest: dto.CreateTaskRequest{Title: "Extract", Description: "Description"}},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, workflow.Tasks, 2)
+		assert.Equal(t, []string{workflow.Tasks[0].ID}, workflow.Tasks[1].DependsOn)
+
+		waitForStatus(t, repo, workflow.Tasks[1].ID, model.StatusCompleted)
+		got, err := service.GetWorkflow(workflow.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.WorkflowCompleted, got.Status())
+	})
+
+	t.Run("stale update", func(t *testing.T) {
+		repo := newRepo(t)
+		service := newInstantService(t, repo, DefaultConfig())
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+		require.NoError(t, err)
+		waitForStatus(t, repo, task.ID, model.StatusCompleted)
+
+		// Копия, прочитанная до обработки, не может перезаписать результат
+		task.Status = model.StatusPending
+		_, err = repo.UpdateTask(task)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		waitForStatus(t, repo, task.ID, model.StatusCompleted)
+	})
+}
+
+func TestServiceBackends(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testServiceBackend(t, func(_ *testing.T) repository.TaskRepositoryInterface {
+			return repository.NewTaskRepository()
+		})
+	})
+
+	t.Run("file", func(t *testing.T) {
+		testServiceBackend(t, func(t *testing.T) repository.TaskRepositoryInterface {
+			repo, err := repository.NewFileTaskRepository(repository.FileRepositoryConfig{Dir: t.TempDir()})
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		testServiceBackend(t, func(t *testing.T) repository.TaskRepositoryInterface {
+			repo, err := repository.NewSQLiteTaskRepository(repository.SQLiteRepositoryConfig{Path: filepath.Join(t.TempDir(), "tasks.db")})
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		})
+	})
+}
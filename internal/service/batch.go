@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// MaxBatchSize is the largest number of tasks created by one batch request
+const MaxBatchSize = 1000
+
+// BatchMode decides what happens to the valid items of a batch when others fail
+type BatchMode string
+
+const (
+	// BatchAllOrNothing creates no task unless every item is valid
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort creates every valid item
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+var (
+	ErrInvalidBatch     = errors.New("invalid task batch")
+	ErrBatchRejected    = errors.New("task batch rejected")
+	ErrMissingTaskField = errors.New("title and description are required")
+)
+
+// BatchItemResult is the outcome of one item of a batch. Task is set if the
+// item was created, Err if it failed; neither is set for valid items of a
+// rejected all-or-nothing batch.
+type BatchItemResult struct {
+	Task *model.Task
+	Err  error
+}
+
+// BatchResult holds the outcome of every item, in request order
+type BatchResult struct {
+	Mode    BatchMode
+	Items   []BatchItemResult
+	Created int
+	Failed  int
+}
+
+// CreateTaskBatch validates and stores a batch of tasks. The created tasks
+// are handed to the workers only once the whole batch has been stored. An
+// all-or-nothing batch is stored with a single repository write, so it is
+// stored completely or not at all; if it has an invalid item, it returns
+// ErrBatchRejected along with the result, which tells the items apart.
+func (s *TaskService) CreateTaskBatch(ctx context.Context, mode BatchMode, reqs []dto.CreateTaskRequest) (*BatchResult, error) {
+	switch mode {
+	case "":
+		mode = BatchAllOrNothing
+	case BatchAllOrNothing, BatchBestEffort:
+	default:
+		return nil, errors.Wrapf(ErrInvalidBatch, "unknown mode %q", mode)
+	}
+
+	if len(reqs) == 0 || len(reqs) > MaxBatchSize {
+		return nil, errors.Wrapf(ErrInvalidBatch, "a batch must hold between 1 and %d tasks", MaxBatchSize)
+	}
+
+	result := &BatchResult{Mode: mode, Items: make([]BatchItemResult, len(reqs))}
+	tasks := make([]*model.Task, len(reqs))
+	for i, req := range reqs {
+		if strings.TrimSpace(req.Title) == "" || strings.TrimSpace(req.Description) == "" {
+			result.fail(i, ErrMissingTaskField)
+			continue
+		}
+
+		task, err := s.newTask(req)
+		if err != nil {
+			result.fail(i, err)
+			continue
+		}
+		tasks[i] = task
+	}
+
+	if mode == BatchAllOrNothing && result.Failed > 0 {
+		return result, ErrBatchRejected
+	}
+
+	if mode == BatchAllOrNothing {
+		if err := s.storeBatch(tasks, result); err != nil {
+			return nil, err
+		}
+	} else {
+		s.storeEach(tasks, result)
+	}
+
+	for _, item := range result.Items {
+		if item.Task != nil {
+			s.dispatch(item.Task)
+		}
+	}
+
+	s.logger.Info("Task batch created",
+		zap.String("mode", string(mode)),
+		zap.Int("created", result.Created),
+		zap.Int("failed", result.Failed))
+
+	return result, nil
+}
+
+func (r *BatchResult) fail(i int, err error) {
+	r.Items[i].Err = err
+	r.Failed++
+}
+
+// storeBatch admits and stores the tasks of an all-or-nothing batch as a whole
+func (s *TaskService) storeBatch(tasks []*model.Task, result *BatchResult) error {
+	ready := readyTasks(tasks)
+	if err := s.admit(ready); err != nil {
+		return err
+	}
+
+	created, err := s.repo.CreateTasks(tasks)
+	if err != nil {
+		s.queue.Unreserve(ready)
+		return errors.Wrap(err, "create tasks")
+	}
+
+	for i, task := range created {
+		result.Items[i].Task = task
+	}
+	result.Created = len(created)
+	return nil
+}
+
+// storeEach admits and stores the valid tasks of a best effort batch one by
+// one, recording the items that could not be stored
+func (s *TaskService) storeEach(tasks []*model.Task, result *BatchResult) {
+	for i, task := range tasks {
+		if task == nil {
+			continue
+		}
+
+		ready := readyTasks([]*model.Task{task})
+		if err := s.admit(ready); err != nil {
+			result.fail(i, err)
+			continue
+		}
+
+		created, err := s.repo.CreateTask(task)
+		if err != nil {
+			s.queue.Unreserve(ready)
+			result.fail(i, errors.Wrap(err, "create task"))
+			continue
+		}
+
+		result.Items[i].Task = created
+		result.Created++
+	}
+}
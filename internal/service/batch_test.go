@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestCreateTaskBatch(t *testing.T) {
+	priority := 42
+	reqs := []dto.CreateTaskRequest{
+		{Title: "First", Description: "Description"},
+		{Title: "Invalid", Description: "Description", Priority: &priority},
+		{Title: "", Description: "Description"},
+		{Title: "Last", Description: "Description"},
+	}
+
+	t.Run("all or nothing", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		service := newInstantService(t, repo, DefaultConfig())
+
+		result, err := service.CreateTaskBatch(context.Background(), "", reqs)
+		assert.ErrorIs(t, err, ErrBatchRejected)
+		require.NotNil(t, result)
+		assert.Equal(t, BatchAllOrNothing, result.Mode)
+		assert.Equal(t, 0, result.Created)
+		assert.Equal(t, 2, result.Failed)
+		assert.NoError(t, result.Items[0].Err)
+		assert.Nil(t, result.Items[0].Task)
+		assert.ErrorIs(t, result.Items[1].Err, ErrInvalidPriority)
+		assert.ErrorIs(t, result.Items[2].Err, ErrMissingTaskField)
+
+		// Ни одна задача не создана
+		tasks, err := repo.ListTasks()
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+
+		result, err = service.CreateTaskBatch(context.Background(), BatchAllOrNothing, []dto.CreateTaskRequest{reqs[0], reqs[3]})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		for _, item := range result.Items {
+			require.NotNil(t, item.Task)
+			waitForStatus(t, repo, item.Task.ID, model.StatusCompleted)
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		service := newInstantService(t, repo, DefaultConfig())
+
+		result, err := service.CreateTaskBatch(context.Background(), BatchBestEffort, reqs)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, 2, result.Failed)
+
+		require.NotNil(t, result.Items[0].Task)
+		require.NotNil(t, result.Items[3].Task)
+		assert.Equal(t, "1", result.Items[0].Task.ID)
+		assert.Equal(t, "2", result.Items[3].Task.ID)
+		assert.Error(t, result.Items[1].Err)
+		assert.Error(t, result.Items[2].Err)
+
+		waitForStatus(t, repo, "1", model.StatusCompleted)
+		waitForStatus(t, repo, "2", model.StatusCompleted)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		mockRepo := newMockRepository(ctrl)
+		// Пакет сохраняется одной записью, поэтому при ошибке в хранилище не остаётся ни одной задачи
+		mockRepo.EXPECT().CreateTasks(gomock.Len(2)).Return(nil, errors.New("disk full"))
+
+		config := DefaultConfig()
+		config.Queue.Capacity = 2
+		service := newInstantService(t, mockRepo, config)
+
+		_, err := service.CreateTaskBatch(context.Background(), BatchAllOrNothing, []dto.CreateTaskRequest{reqs[0], reqs[3]})
+		assert.Error(t, err)
+
+		// Зарезервированные места в очереди возвращаются
+		assert.True(t, service.queue.Reserve(2))
+		service.queue.Unreserve(2)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		service := newInstantService(t, repository.NewTaskRepository(), DefaultConfig())
+
+		_, err := service.CreateTaskBatch(context.Background(), "partial", reqs)
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+		_, err = service.CreateTaskBatch(context.Background(), BatchBestEffort, nil)
+		assert.ErrorIs(t, err, ErrInvalidBatch)
+	})
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// DefaultCancelReason is recorded when a task is cancelled without a reason
+const DefaultCancelReason = "cancelled by user"
+
+var ErrTaskNotCancellable = errors.New("task has already finished")
+
+// runningTask tracks a task that a worker of this service is processing
+type runningTask struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	// reason is set when the task is cancelled through CancelTask
+	reason    string
+	cancelled bool
+	// progress is the latest progress reported by the executor
+	progress *model.TaskProgress
+}
+
+// startRunning records that a worker is about to process the task and returns
+// the context the task runs with, which carries the task's progress reporter.
+// It returns false if the task was cancelled while it was waiting in the queue.
+func (s *TaskService) startRunning(ctx context.Context, task *model.Task) (context.Context, bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	id := task.ID
+	if _, ok := s.cancelled[id]; ok {
+		delete(s.cancelled, id)
+		return nil, false
+	}
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	running := &runningTask{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.running[id] = running
+
+	reporter := &progressReporter{service: s, task: task, running: running, started: time.Now()}
+	return context.WithValue(taskCtx, progressKey{}, reporter), true
+}
+
+// finishRunning forgets a task recorded by startRunning and wakes up anyone
+// waiting for its cancellation
+func (s *TaskService) finishRunning(id string) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	running, ok := s.running[id]
+	if !ok {
+		return
+	}
+
+	running.cancel()
+	close(running.done)
+	delete(s.running, id)
+}
+
+// cancellation returns the reason the running task was cancelled, if it was
+func (s *TaskService) cancellation(id string) (string, bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	running, ok := s.running[id]
+	if !ok || !running.cancelled {
+		return "", false
+	}
+	return running.reason, true
+}
+
+func (s *TaskService) isRunning(id string) bool {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	_, ok := s.running[id]
+	return ok
+}
+
+// CancelTask stops a task that has not finished yet. A waiting task is taken
+// out of the queue or the delay queue; a running task has its context
+// cancelled and the call waits until the worker has recorded the cancellation.
+func (s *TaskService) CancelTask(ctx context.Context, id, reason string) (*model.Task, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidTaskID
+	}
+
+	if reason == "" {
+		reason = DefaultCancelReason
+	}
+
+	s.runningMu.Lock()
+	if running, ok := s.running[id]; ok {
+		running.cancelled = true
+		running.reason = reason
+		running.cancel()
+		s.runningMu.Unlock()
+
+		return s.waitCancelled(ctx, id, running)
+	}
+	task, err := s.cancelWaiting(id, reason)
+	s.runningMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Task cancelled",
+		zap.String("task_id", task.ID),
+		zap.String("reason", reason))
+
+	return task, nil
+}
+
+// cancelWaiting cancels a task that no worker is running. The caller holds
+// runningMu, so the task can not start or change status in between; a worker
+// stores the outcome of a task before it stops running it. A pending task that
+// is in neither queue is on its way to a worker, which is told to skip it.
+func (s *TaskService) cancelWaiting(id, reason string) (*model.Task, error) {
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get task")
+	}
+
+	if task.Status.IsTerminal() {
+		return nil, ErrTaskNotCancellable
+	}
+
+	status := task.Status
+	task.Cancel(reason)
+	if err := s.updateTask(task); err != nil {
+		return nil, errors.Wrap(err, "update task")
+	}
+
+	_, queued := s.queue.Remove(id)
+	_, delayed := s.delayed.Remove(id)
+	if status == model.StatusPending && !queued && !delayed {
+		s.cancelled[id] = struct{}{}
+	}
+	return task, nil
+}
+
+// waitCancelled waits for the worker to stop a running task and returns the
+// task as the worker left it
+func (s *TaskService) waitCancelled(ctx context.Context, id string, running *runningTask) (*model.Task, error) {
+	select {
+	case <-running.done:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "wait for cancellation")
+	}
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get task")
+	}
+
+	if task.Status != model.StatusCancelled {
+		return nil, ErrTaskNotCancellable
+	}
+	return task, nil
+}
+
+// recordCancelled stores the outcome of a running task that was cancelled
+func (s *TaskService) recordCancelled(task *model.Task, reason string) {
+	task.Cancel(reason)
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return
+	}
+
+	s.logger.Info("Task cancelled",
+		zap.String("task_id", task.ID),
+		zap.String("reason", reason),
+		zap.String("duration", task.DurationStr))
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// newBlockingService создаёт сервис, задачи которого выполняются до отмены контекста
+func newBlockingService(t *testing.T) (*TaskService, repository.TaskRepositoryInterface) {
+	t.Helper()
+
+	repo := repository.NewTaskRepository()
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}),
+	}
+
+	service := NewTaskService(repo, setupTestLogger(), config)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+	return service, repo
+}
+
+func TestCancelTask(t *testing.T) {
+	t.Run("running", func(t *testing.T) {
+		service, repo := newBlockingService(t)
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+		require.NoError(t, err)
+		waitForStatus(t, repo, task.ID, model.StatusProcessing)
+
+		cancelled, err := service.CancelTask(context.Background(), task.ID, "no longer needed")
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCancelled, cancelled.Status)
+		assert.Equal(t, "no longer needed", cancelled.CancelReason)
+		assert.NotNil(t, cancelled.CancelledAt)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		service, repo := newBlockingService(t)
+
+		// Занимаем все воркеры, чтобы следующая задача осталась в очереди
+		for i := 0; i < service.WorkerCount(); i++ {
+			task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Busy", Description: "Description"})
+			require.NoError(t, err)
+			waitForStatus(t, repo, task.ID, model.StatusProcessing)
+		}
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Queued", Description: "Description"})
+		require.NoError(t, err)
+
+		cancelled, err := service.CancelTask(context.Background(), task.ID, "")
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCancelled, cancelled.Status)
+		assert.Equal(t, DefaultCancelReason, cancelled.CancelReason)
+		assert.Nil(t, cancelled.StartedAt)
+
+		// Отменённая задача сразу уходит из очереди и не остаётся в списке отменённых
+		assert.Zero(t, service.QueueDepth())
+		assert.Empty(t, service.cancelled)
+	})
+
+	t.Run("scheduled", func(t *testing.T) {
+		service, _ := newBlockingService(t)
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Later", Description: "Description", Delay: "1h"})
+		require.NoError(t, err)
+		blocked, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Blocked", Description: "Description", DependsOn: []string{task.ID}})
+		require.NoError(t, err)
+
+		_, err = service.CancelTask(context.Background(), blocked.ID, "")
+		require.NoError(t, err)
+		_, err = service.CancelTask(context.Background(), task.ID, "")
+		require.NoError(t, err)
+
+		assert.Zero(t, service.delayed.Len())
+		assert.Empty(t, service.cancelled)
+	})
+
+	t.Run("finished", func(t *testing.T) {
+		service, repo := newBlockingService(t)
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+		require.NoError(t, err)
+		waitForStatus(t, repo, task.ID, model.StatusProcessing)
+
+		_, err = service.CancelTask(context.Background(), task.ID, "")
+		require.NoError(t, err)
+
+		_, err = service.CancelTask(context.Background(), task.ID, "")
+		assert.ErrorIs(t, err, ErrTaskNotCancellable)
+	})
+}
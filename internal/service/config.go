@@ -0,0 +1,89 @@
+package service
+
+import (
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// Config holds task service configuration
+type Config struct {
+	// MaxResultSize is the maximum size of a task result in bytes, 0 disables the limit
+	MaxResultSize int
+	// Executors are registered before the workers start and recovered tasks
+	// are queued, in addition to the built-in DefaultTaskType executor
+	Executors  map[string]TaskExecutor
+	Recovery   RecoveryConfig
+	Retry      RetryConfig
+	Timeout    TimeoutConfig
+	Queue      QueueConfig
+	Schedules  ScheduleConfig
+	Events     EventConfig
+	Webhooks   WebhookConfig
+	Autoscaler AutoscalerConfig
+	// IdempotencyWindow is how long a repeated request with the same
+	// idempotency key returns the task it created. 0 ignores the keys.
+	IdempotencyWindow time.Duration
+}
+
+// DefaultConfig returns default task service configuration
+func DefaultConfig() Config {
+	return Config{
+		MaxResultSize: 1 << 20, // 1 MB
+		Recovery: RecoveryConfig{
+			StalePolicy:  StalePolicyRequeue,
+			ReapInterval: time.Minute,
+			StaleAfter:   5 * time.Minute,
+		},
+		Retry: RetryConfig{
+			DefaultMaxAttempts: 1,
+			MaxAttempts:        10,
+			Backoff: model.BackoffPolicy{
+				Initial:    time.Second,
+				Multiplier: 2,
+				Max:        5 * time.Minute,
+				Jitter:     0.2,
+			},
+		},
+		Timeout: TimeoutConfig{
+			Default: 10 * time.Minute,
+			Max:     time.Hour,
+		},
+		Queue: QueueConfig{
+			Capacity:      100,
+			AgingInterval: 30 * time.Second,
+			FullPolicy:    QueueFullReject,
+		},
+		Schedules: ScheduleConfig{
+			MissedRunGrace: time.Minute,
+			MaxCatchUpRuns: 100,
+		},
+		Events: EventConfig{
+			HistorySize:      1000,
+			SubscriberBuffer: 64,
+		},
+		Webhooks: WebhookConfig{
+			Timeout:     10 * time.Second,
+			MaxAttempts: 5,
+			Backoff: model.BackoffPolicy{
+				Initial:    time.Second,
+				Multiplier: 2,
+				Max:        5 * time.Minute,
+				Jitter:     0.2,
+			},
+			Workers:           4,
+			DeliveryRetention: 24 * time.Hour,
+			MaxDeliveries:     10000,
+		},
+		Autoscaler: AutoscalerConfig{
+			MinWorkers:        2,
+			MaxWorkers:        20,
+			Interval:          time.Second,
+			ScaleUpQueueDepth: 10,
+			ScaleUpLatency:    5 * time.Second,
+			ScaleUpCooldown:   10 * time.Second,
+			ScaleDownCooldown: 2 * time.Minute,
+		},
+		IdempotencyWindow: 24 * time.Hour,
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+var ErrInvalidSchedule = errors.New("invalid task schedule")
+
+// delayedTask is a task waiting for its due time
+type delayedTask struct {
+	id    string
+	dueAt time.Time
+	seq   uint64
+}
+
+// delayHeap implements heap.Interface ordered by due time
+type delayHeap []*delayedTask
+
+func (h delayHeap) Len() int { return len(h) }
+
+func (h delayHeap) Less(i, j int) bool {
+	if !h[i].dueAt.Equal(h[j].dueAt) {
+		return h[i].dueAt.Before(h[j].dueAt)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h delayHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap) Push(x any) { *h = append(*h, x.(*delayedTask)) }
+
+func (h *delayHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// delayQueue holds tasks that must not run before a given time: scheduled
+// tasks and tasks waiting to be retried. A single timer is armed for the
+// earliest due time.
+type delayQueue struct {
+	mu    sync.Mutex
+	items delayHeap
+	seq   uint64
+	// wake is signalled when a task that may be due earlier is added
+	wake chan struct{}
+}
+
+func newDelayQueue() *delayQueue {
+	return &delayQueue{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Add holds the task until dueAt
+func (d *delayQueue) Add(id string, dueAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	heap.Push(&d.items, &delayedTask{id: id, dueAt: dueAt, seq: d.seq})
+	signal(d.wake)
+}
+
+// Remove takes the task out of the queue and returns the time it was due at
+func (d *delayQueue) Remove(id string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, item := range d.items {
+		if item.id == id {
+			heap.Remove(&d.items, i)
+			return item.dueAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// due removes the tasks whose time has come and returns how long to wait
+// for the next one
+func (d *delayQueue) due(now time.Time) ([]string, time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var ids []string
+	for len(d.items) > 0 && !d.items[0].dueAt.After(now) {
+		ids = append(ids, heap.Pop(&d.items).(*delayedTask).id)
+	}
+
+	if len(d.items) == 0 {
+		return ids, time.Hour
+	}
+	return ids, d.items[0].dueAt.Sub(now)
+}
+
+// Len returns the number of waiting tasks
+func (d *delayQueue) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.items)
+}
+
+// runDelayQueue hands delayed tasks to the worker pool when they are due
+func (s *TaskService) runDelayQueue() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-s.delayed.wake:
+		case <-timer.C:
+		}
+
+		ids, wait := s.delayed.due(time.Now())
+		for _, id := range ids {
+			s.releaseTask(id)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// releaseTask queues a delayed task whose time has come
+func (s *TaskService) releaseTask(id string) {
+	if task, ok := s.dueTask(id); ok {
+		s.enqueue(task)
+	}
+}
+
+// dueTask reads a delayed task whose time has come again, because it may have
+// been cancelled or deleted while it waited, and makes a scheduled task
+// pending. It returns the task if it is to be queued. Holding runningMu keeps
+// CancelTask from cancelling the task in between, which would be overwritten.
+func (s *TaskService) dueTask(id string) (*model.Task, bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		s.logger.Info("Delayed task is gone", zap.String("task_id", id), zap.Error(err))
+		delete(s.cancelled, id)
+		return nil, false
+	}
+
+	switch task.Status {
+	case model.StatusScheduled:
+		task.Status = model.StatusPending
+		if err := s.updateTask(task); err != nil {
+			s.logger.Error("Failed to update task status", err,
+				zap.String("task_id", task.ID))
+			return nil, false
+		}
+		s.logger.Info("Scheduled task is due", zap.String("task_id", task.ID))
+	case model.StatusPending:
+	default:
+		delete(s.cancelled, id)
+		return nil, false
+	}
+
+	return task, true
+}
+
+// taskRunAt resolves when a new task may start from run_at or delay.
+// It returns nil if the task may start right away.
+func taskRunAt(req dto.CreateTaskRequest) (*time.Time, error) {
+	if req.RunAt != "" && req.Delay != "" {
+		return nil, errors.Wrap(ErrInvalidSchedule, "run_at and delay are mutually exclusive")
+	}
+
+	var runAt time.Time
+	switch {
+	case req.RunAt != "":
+		parsed, err := time.Parse(time.RFC3339, req.RunAt)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidSchedule, "run_at %q is not an RFC3339 time", req.RunAt)
+		}
+		runAt = parsed
+	case req.Delay != "":
+		delay, err := time.ParseDuration(req.Delay)
+		if err != nil || delay < 0 {
+			return nil, errors.Wrapf(ErrInvalidSchedule, "delay %q is not a non-negative duration", req.Delay)
+		}
+		runAt = time.Now().Add(delay)
+	default:
+		return nil, nil
+	}
+
+	if !runAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &runAt, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// newInstantService создаёт сервис, исполнитель которого сразу завершает задачи
+func newInstantService(t *testing.T, repo repository.TaskRepositoryInterface, config Config) *TaskService {
+	t.Helper()
+
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+			return TextResult("done"), nil
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+	return service
+}
+
+func TestScheduledTask(t *testing.T) {
+	t.Run("delay", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		service := newInstantService(t, repo, DefaultConfig())
+
+		created := time.Now()
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+			Title:       "Task",
+			Description: "Description",
+			Delay:       "100ms",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusScheduled, task.Status)
+		require.NotNil(t, task.RunAt)
+
+		task = waitForStatus(t, repo, task.ID, model.StatusCompleted)
+		// Задача не должна стартовать раньше назначенного времени
+		assert.False(t, task.StartedAt.Before(created.Add(100*time.Millisecond)))
+	})
+
+	t.Run("run_at in the past", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		service := newInstantService(t, repo, DefaultConfig())
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+			Title:       "Task",
+			Description: "Description",
+			RunAt:       time.Now().Add(-time.Hour).Format(time.RFC3339),
+		})
+		require.NoError(t, err)
+		assert.Nil(t, task.RunAt)
+
+		waitForStatus(t, repo, task.ID, model.StatusCompleted)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		service := newInstantService(t, repo, DefaultConfig())
+
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+			Title:       "Task",
+			Description: "Description",
+			Delay:       "50ms",
+		})
+		require.NoError(t, err)
+
+		_, err = service.CancelTask(context.Background(), task.ID, "")
+		require.NoError(t, err)
+
+		time.Sleep(150 * time.Millisecond)
+		task = waitForStatus(t, repo, task.ID, model.StatusCancelled)
+		assert.Nil(t, task.StartedAt)
+	})
+
+	t.Run("recovered", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		stored := createStoredTask(t, repo, model.StatusPending)
+		runAt := time.Now().Add(100 * time.Millisecond)
+		stored.Status = model.StatusScheduled
+		stored.RunAt = &runAt
+		_, err := repo.UpdateTask(stored)
+		require.NoError(t, err)
+
+		retryAt := time.Now().Add(100 * time.Millisecond)
+		retrying := createStoredTask(t, repo, model.StatusPending)
+		retrying.NextRetryAt = &retryAt
+		_, err = repo.UpdateTask(retrying)
+		require.NoError(t, err)
+
+		pending := createStoredTask(t, repo, model.StatusPending)
+		stale := createStoredTask(t, repo, model.StatusProcessing)
+
+		// Отложенные и ждущие задачи возвращаются в очередь даже без восстановления
+		config := DefaultConfig()
+		config.Recovery.Enabled = false
+		newInstantService(t, repo, config)
+
+		task := waitForStatus(t, repo, stored.ID, model.StatusCompleted)
+		assert.False(t, task.StartedAt.Before(runAt))
+		task = waitForStatus(t, repo, retrying.ID, model.StatusCompleted)
+		assert.False(t, task.StartedAt.Before(retryAt))
+		waitForStatus(t, repo, pending.ID, model.StatusCompleted)
+
+		// Зависшие задачи без восстановления не трогаются
+		task, err = repo.GetTask(stale.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusProcessing, task.Status)
+	})
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	service := newInstantService(t, repository.NewTaskRepository(), DefaultConfig())
+
+	requests := map[string]dto.CreateTaskRequest{
+		"both":           {RunAt: time.Now().Format(time.RFC3339), Delay: "1m"},
+		"bad run_at":     {RunAt: "tomorrow"},
+		"bad delay":      {Delay: "soon"},
+		"negative delay": {Delay: "-1m"},
+	}
+
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			req.Title = "Task"
+			req.Description = "Description"
+
+			_, err := service.CreateTask(context.Background(), req)
+			assert.ErrorIs(t, err, ErrInvalidSchedule)
+		})
+	}
+}
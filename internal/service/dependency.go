@@ -0,0 +1,210 @@
+package service
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+var (
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrDependencyFailed   = errors.New("dependency did not complete")
+)
+
+// dependencyTracker remembers which blocked tasks wait for which tasks and
+// collects the blocked tasks that have to be checked again. The checks are
+// done one at a time by the service's dependency resolver, so a blocked task
+// is never released twice.
+type dependencyTracker struct {
+	mu sync.Mutex
+	// dependents maps a task ID to the blocked tasks waiting for it
+	dependents map[string][]string
+	// unchecked are the blocked tasks whose dependencies may have changed
+	unchecked []string
+	wake      chan struct{}
+}
+
+func newDependencyTracker() *dependencyTracker {
+	return &dependencyTracker{
+		dependents: make(map[string][]string),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// watch registers a blocked task with its dependencies and has it checked,
+// in case they finished before it was registered
+func (d *dependencyTracker) watch(task *model.Task) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, id := range task.DependsOn {
+		d.dependents[id] = append(d.dependents[id], task.ID)
+	}
+	d.unchecked = append(d.unchecked, task.ID)
+	signal(d.wake)
+}
+
+// finished has the tasks waiting for id checked again
+func (d *dependencyTracker) finished(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	waiting, ok := d.dependents[id]
+	if !ok {
+		return
+	}
+
+	delete(d.dependents, id)
+	d.unchecked = append(d.unchecked, waiting...)
+	signal(d.wake)
+}
+
+// take returns the tasks to check and forgets them
+func (d *dependencyTracker) take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := d.unchecked
+	d.unchecked = nil
+	return ids
+}
+
+// runDependencyResolver releases blocked tasks once all their dependencies
+// have completed and fails them when one does not
+func (s *TaskService) runDependencyResolver() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-s.dependencies.wake:
+		}
+
+		for _, id := range s.dependencies.take() {
+			s.resolveDependencies(id)
+		}
+	}
+}
+
+// resolveDependencies checks a blocked task against its dependencies
+func (s *TaskService) resolveDependencies(id string) {
+	if task, ok := s.unblockTask(id); ok {
+		s.enqueue(task)
+	}
+}
+
+// unblockTask moves a blocked task on once its dependencies have completed,
+// or fails it if one of them did not. It returns the task if it is to be
+// queued. Holding runningMu keeps CancelTask from cancelling the task in
+// between, which would be overwritten.
+func (s *TaskService) unblockTask(id string) (*model.Task, bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		s.logger.Info("Blocked task is gone", zap.String("task_id", id), zap.Error(err))
+		return nil, false
+	}
+
+	if task.Status != model.StatusBlocked {
+		return nil, false
+	}
+
+	ready, depErr := s.dependenciesReady(task)
+	if depErr != nil {
+		s.failTask(task, depErr)
+		return nil, false
+	}
+	if !ready {
+		return nil, false
+	}
+
+	s.logger.Info("Task dependencies completed", zap.String("task_id", task.ID))
+
+	if task.RunAt != nil && task.RunAt.After(time.Now()) {
+		task.Status = model.StatusScheduled
+		if err := s.updateTask(task); err != nil {
+			s.logger.Error("Failed to update task status", err,
+				zap.String("task_id", task.ID))
+			return nil, false
+		}
+		s.delayed.Add(task.ID, *task.RunAt)
+		return nil, false
+	}
+
+	task.Status = model.StatusPending
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return nil, false
+	}
+
+	return task, true
+}
+
+// dependenciesReady reports whether every dependency of the task has
+// completed. It returns an ErrDependencyFailed error naming the first
+// dependency that failed, was cancelled or was deleted.
+func (s *TaskService) dependenciesReady(task *model.Task) (bool, error) {
+	ready := true
+	for _, id := range task.DependsOn {
+		dependency, err := s.repo.GetTask(id)
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return false, errors.Wrapf(ErrDependencyFailed, "dependency %s was deleted", id)
+		}
+		if err != nil {
+			s.logger.Error("Failed to get dependency", err,
+				zap.String("task_id", task.ID),
+				zap.String("dependency_id", id))
+			return false, nil
+		}
+
+		switch dependency.Status {
+		case model.StatusCompleted:
+		case model.StatusCancelled:
+			return false, errors.Wrapf(ErrDependencyFailed, "dependency %s was cancelled", id)
+		case model.StatusFailed, model.StatusDeadLetter:
+			return false, errors.Wrapf(ErrDependencyFailed, "dependency %s failed", id)
+		default:
+			ready = false
+		}
+	}
+	return ready, nil
+}
+
+// taskDependencies checks that every dependency of a new task exists and
+// returns them without duplicates
+func (s *TaskService) taskDependencies(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{}, len(ids))
+	dependencies := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, errors.Wrapf(ErrDependencyNotFound, "%q", id)
+		}
+		if _, err := s.repo.GetTask(id); err != nil {
+			if errors.Is(err, repository.ErrTaskNotFound) {
+				return nil, errors.Wrapf(ErrDependencyNotFound, "%q", id)
+			}
+			return nil, errors.Wrap(err, "get dependency")
+		}
+		dependencies = append(dependencies, id)
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// newDependencyService создаёт сервис, исполнитель которого завершает задачу ошибкой,
+// если в payload передано {"fail": true}
+func newDependencyService(t *testing.T) (*TaskService, repository.TaskRepositoryInterface) {
+	t.Helper()
+
+	repo := repository.NewTaskRepository()
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, task *model.Task) (json.RawMessage, error) {
+			var payload struct {
+				Fail bool `json:"fail"`
+			}
+			_ = json.Unmarshal(task.Payload, &payload)
+			if payload.Fail {
+				return nil, errors.New("boom")
+			}
+			return TextResult("done"), nil
+		}),
+	}
+
+	service := NewTaskService(repo, setupTestLogger(), config)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+	return service, repo
+}
+
+func dependentTask(title string, dependsOn ...string) dto.CreateTaskRequest {
+	return dto.CreateTaskRequest{Title: title, Description: "Description", DependsOn: dependsOn}
+}
+
+func TestTaskDependencies(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		service, repo := newDependencyService(t)
+
+		first, err := service.CreateTask(context.Background(), dependentTask("First"))
+		require.NoError(t, err)
+		second, err := service.CreateTask(context.Background(), dependentTask("Second"))
+		require.NoError(t, err)
+
+		task, err := service.CreateTask(context.Background(), dependentTask("Dependent", first.ID, second.ID))
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusBlocked, task.Status)
+
+		task = waitForStatus(t, repo, task.ID, model.StatusCompleted)
+		first = waitForStatus(t, repo, first.ID, model.StatusCompleted)
+		second = waitForStatus(t, repo, second.ID, model.StatusCompleted)
+		assert.False(t, task.StartedAt.Before(*first.CompletedAt))
+		assert.False(t, task.StartedAt.Before(*second.CompletedAt))
+	})
+
+	t.Run("failed dependency", func(t *testing.T) {
+		service, repo := newDependencyService(t)
+
+		req := dependentTask("Failing")
+		req.Payload = json.RawMessage(`{"fail": true}`)
+		failing, err := service.CreateTask(context.Background(), req)
+		require.NoError(t, err)
+
+		dependent, err := service.CreateTask(context.Background(), dependentTask("Dependent", failing.ID))
+		require.NoError(t, err)
+		// Ошибка распространяется по цепочке зависимостей
+		transitive, err := service.CreateTask(context.Background(), dependentTask("Transitive", dependent.ID))
+		require.NoError(t, err)
+
+		dependent = waitForStatus(t, repo, dependent.ID, model.StatusFailed)
+		assert.Equal(t, model.FailureReasonDependencyFailed, dependent.FailureReason)
+		assert.Contains(t, dependent.Error, "dependency "+failing.ID+" failed")
+		assert.Nil(t, dependent.StartedAt)
+
+		transitive = waitForStatus(t, repo, transitive.ID, model.StatusFailed)
+		assert.Contains(t, transitive.Error, "dependency "+dependent.ID+" failed")
+	})
+
+	t.Run("cancelled dependency", func(t *testing.T) {
+		service, repo := newDependencyService(t)
+
+		req := dependentTask("Later")
+		req.Delay = "1h"
+		later, err := service.CreateTask(context.Background(), req)
+		require.NoError(t, err)
+
+		dependent, err := service.CreateTask(context.Background(), dependentTask("Dependent", later.ID))
+		require.NoError(t, err)
+
+		_, err = service.CancelTask(context.Background(), later.ID, "")
+		require.NoError(t, err)
+
+		dependent = waitForStatus(t, repo, dependent.ID, model.StatusFailed)
+		assert.Contains(t, dependent.Error, "dependency "+later.ID+" was cancelled")
+	})
+
+	t.Run("restarted", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		dependency := createStoredTask(t, repo, model.StatusPending)
+		blocked := createStoredTask(t, repo, model.StatusBlocked)
+		blocked.DependsOn = []string{dependency.ID}
+		_, err := repo.UpdateTask(blocked)
+		require.NoError(t, err)
+
+		// Заблокированные задачи снова ждут зависимостей и без восстановления
+		config := DefaultConfig()
+		config.Recovery.Enabled = false
+		newInstantService(t, repo, config)
+
+		dependency = waitForStatus(t, repo, dependency.ID, model.StatusCompleted)
+		blocked = waitForStatus(t, repo, blocked.ID, model.StatusCompleted)
+		assert.False(t, blocked.StartedAt.Before(*dependency.CompletedAt))
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		service, _ := newDependencyService(t)
+
+		_, err := service.CreateTask(context.Background(), dependentTask("Dependent", "42"))
+		assert.ErrorIs(t, err, ErrDependencyNotFound)
+	})
+}
+
+func workflowTask(key string, dependsOn ...string) dto.WorkflowTaskRequest {
+	return dto.WorkflowTaskRequest{Key: key, CreateTaskRequest: dependentTask(key, dependsOn...)}
+}
+
+func TestWorkflow(t *testing.T) {
+	t.Run("diamond", func(t *testing.T) {
+		service, repo := newDependencyService(t)
+
+		// Порядок в запросе не важен: задачи создаются в топологическом порядке
+		workflow, err := service.CreateWorkflow(context.Background(), dto.CreateWorkflowRequest{
+			Tasks: []dto.WorkflowTaskRequest{
+				workflowTask("report", "left", "right"),
+				workflowTask("left", "extract"),
+				workflowTask("right", "extract"),
+				workflowTask("extract"),
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, workflow.Tasks, 4)
+		assert.Equal(t, "extract", workflow.Tasks[0].WorkflowKey)
+		assert.Equal(t, "report", workflow.Tasks[3].WorkflowKey)
+		assert.Len(t, workflow.Tasks[3].DependsOn, 2)
+
+		waitForStatus(t, repo, workflow.Tasks[3].ID, model.StatusCompleted)
+
+		got, err := service.GetWorkflow(workflow.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.WorkflowCompleted, got.Status())
+
+		resp := dto.NewWorkflowResponse(got)
+		assert.Equal(t, 4, resp.Counts[string(model.StatusCompleted)])
+		assert.ElementsMatch(t, []string{"left", "right"}, resp.Tasks[3].DependsOn)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		service, repo := newDependencyService(t)
+
+		_, err := service.CreateWorkflow(context.Background(), dto.CreateWorkflowRequest{
+			Tasks: []dto.WorkflowTaskRequest{
+				workflowTask("a", "c"),
+				workflowTask("b", "a"),
+				workflowTask("c", "b"),
+				workflowTask("d"),
+			},
+		})
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+		assert.Contains(t, err.Error(), "a, b, c")
+
+		// Отклонённый граф не создаёт ни одной задачи
+		tasks, err := repo.ListTasks()
+		require.NoError(t, err)
+		assert.Empty(t, tasks)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		service, _ := newDependencyService(t)
+
+		_, err := service.CreateWorkflow(context.Background(), dto.CreateWorkflowRequest{
+			Tasks: []dto.WorkflowTaskRequest{workflowTask("a", "missing")},
+		})
+		assert.ErrorIs(t, err, ErrInvalidWorkflow)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		service, _ := newDependencyService(t)
+
+		_, err := service.GetWorkflow("missing")
+		assert.ErrorIs(t, err, ErrWorkflowNotFound)
+	})
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+var (
+	ErrTaskNotEditable   = errors.New("task can no longer be edited")
+	ErrInvalidTaskUpdate = errors.New("invalid task update")
+)
+
+// isEditable reports whether a task in this status has not started yet
+func isEditable(status model.TaskStatus) bool {
+	return status == model.StatusPending || status == model.StatusScheduled
+}
+
+// EditTask changes a pending or scheduled task and records the change in its
+// history. While the task changes it is held out of the queue it waits in, so
+// no worker can start the old version of it.
+func (s *TaskService) EditTask(id string, req dto.EditTaskRequest) (*model.Task, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidTaskID
+	}
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get task")
+	}
+	if !isEditable(task.Status) {
+		return nil, ErrTaskNotEditable
+	}
+
+	// Reject invalid requests before the task leaves its queue
+	if _, _, err := s.applyEdit(task, req); err != nil {
+		return nil, err
+	}
+
+	queued, inQueue := s.queue.Remove(id)
+	var dueAt time.Time
+	if !inQueue {
+		var delayed bool
+		if dueAt, delayed = s.delayed.Remove(id); !delayed {
+			// A worker or the delay queue has just taken the task
+			return nil, ErrTaskNotEditable
+		}
+	}
+	// putBack returns the unchanged task to where it waited
+	putBack := func() {
+		if inQueue {
+			s.queue.Restore(queued, queued.task)
+		} else {
+			s.delayed.Add(id, dueAt)
+		}
+	}
+
+	edited, previous, err := s.storeEdit(id, req)
+	if err != nil || edited == nil {
+		putBack()
+		return previous, err
+	}
+
+	switch {
+	case edited.Status == model.StatusScheduled:
+		s.delayed.Add(id, *edited.RunAt)
+	case inQueue:
+		s.queue.Restore(queued, edited)
+	case edited.NextRetryAt != nil && previous.Status == model.StatusPending:
+		// Still waiting for its next attempt
+		s.delayed.Add(id, dueAt)
+	default:
+		// A scheduled task that may now run right away. The delay queue
+		// hands it to the workers once there is room for it.
+		s.delayed.Add(id, time.Now())
+	}
+
+	changes := edited.History[len(edited.History)-1].Changes
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	s.logger.Info("Task edited",
+		zap.String("task_id", id),
+		zap.Strings("fields", fields),
+		zap.String("status", string(edited.Status)))
+
+	return edited, nil
+}
+
+// storeEdit applies the request to the stored task and saves it. It returns
+// the edited and the previous version of the task, or no edited version if
+// nothing changed. Holding runningMu keeps CancelTask from cancelling the task
+// in between, which would be overwritten by the edit.
+func (s *TaskService) storeEdit(id string, req dto.EditTaskRequest) (*model.Task, *model.Task, error) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if _, ok := s.cancelled[id]; ok {
+		return nil, nil, ErrTaskNotEditable
+	}
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "get task")
+	}
+	if !isEditable(task.Status) {
+		return nil, nil, ErrTaskNotEditable
+	}
+
+	edited, changes, err := s.applyEdit(task, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changes) == 0 {
+		return nil, task, nil
+	}
+
+	edited.History = append(slices.Clip(task.History), model.TaskEdit{EditedAt: time.Now(), Changes: changes})
+	if err := s.updateTask(edited); err != nil {
+		return nil, nil, errors.Wrap(err, "update task")
+	}
+	return edited, task, nil
+}
+
+// applyEdit validates the request and returns a copy of the task with the
+// requested changes, along with the fields that actually changed
+func (s *TaskService) applyEdit(task *model.Task, req dto.EditTaskRequest) (*model.Task, []model.FieldChange, error) {
+	edited := *task
+	var changes []model.FieldChange
+	record := func(field string, from, to any) {
+		fromJSON, _ := json.Marshal(from)
+		toJSON, _ := json.Marshal(to)
+		if !bytes.Equal(fromJSON, toJSON) {
+			changes = append(changes, model.FieldChange{Field: field, From: fromJSON, To: toJSON})
+		}
+	}
+
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			return nil, nil, errors.Wrap(ErrInvalidTaskUpdate, "title must not be empty")
+		}
+		edited.Title = *req.Title
+		record("title", task.Title, edited.Title)
+	}
+
+	if req.Description != nil {
+		if strings.TrimSpace(*req.Description) == "" {
+			return nil, nil, errors.Wrap(ErrInvalidTaskUpdate, "description must not be empty")
+		}
+		edited.Description = *req.Description
+		record("description", task.Description, edited.Description)
+	}
+
+	if req.Priority != nil {
+		priority, err := taskPriority(dto.CreateTaskRequest{Priority: req.Priority})
+		if err != nil {
+			return nil, nil, err
+		}
+		edited.Priority = priority
+		record("priority", task.Priority, edited.Priority)
+	}
+
+	if req.Payload != nil {
+		payload := req.Payload
+		if bytes.Equal(bytes.TrimSpace(payload), []byte("null")) {
+			payload = nil
+		}
+		if err := s.validateTaskType(task.Type, payload); err != nil {
+			return nil, nil, err
+		}
+		edited.Payload = payload
+		record("payload", task.Payload, edited.Payload)
+	}
+
+	if req.RunAt != nil || req.Delay != nil {
+		schedule := dto.CreateTaskRequest{}
+		if req.RunAt != nil {
+			schedule.RunAt = *req.RunAt
+		}
+		if req.Delay != nil {
+			schedule.Delay = *req.Delay
+		}
+
+		runAt, err := taskRunAt(schedule)
+		if err != nil {
+			return nil, nil, err
+		}
+		edited.RunAt = runAt
+		record("run_at", task.RunAt, edited.RunAt)
+
+		switch {
+		case runAt != nil:
+			edited.Status = model.StatusScheduled
+		case task.Status == model.StatusScheduled:
+			edited.Status = model.StatusPending
+		}
+	}
+
+	return &edited, changes, nil
+}
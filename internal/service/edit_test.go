@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+func TestEditTask(t *testing.T) {
+	service, repo := newBlockingService(t)
+
+	// Занимаем все воркеры, чтобы следующие задачи остались в очереди
+	var busy *model.Task
+	for i := 0; i < service.WorkerCount(); i++ {
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Busy", Description: "Description"})
+		require.NoError(t, err)
+		busy = waitForStatus(t, repo, task.ID, model.StatusProcessing)
+	}
+
+	first, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "First", Description: "Description"})
+	require.NoError(t, err)
+	second, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Second", Description: "Description"})
+	require.NoError(t, err)
+
+	t.Run("pending", func(t *testing.T) {
+		title, priority := "Urgent", 9
+		edited, err := service.EditTask(second.ID, dto.EditTaskRequest{
+			Title:    &title,
+			Priority: &priority,
+			Payload:  json.RawMessage(`{"n":1}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Urgent", edited.Title)
+		assert.Equal(t, 9, edited.Priority)
+		assert.JSONEq(t, `{"n":1}`, string(edited.Payload))
+
+		require.Len(t, edited.History, 1)
+		changes := edited.History[0].Changes
+		require.Len(t, changes, 3)
+		assert.Equal(t, "title", changes[0].Field)
+		assert.JSONEq(t, `"Second"`, string(changes[0].From))
+		assert.JSONEq(t, `"Urgent"`, string(changes[0].To))
+
+		// Повышенный приоритет переносит задачу в начало очереди
+		assert.Equal(t, 2, service.queue.Len())
+		task, ok := service.queue.tryPop()
+		require.True(t, ok)
+		assert.Equal(t, second.ID, task.ID)
+		assert.Equal(t, "Urgent", task.Title)
+		service.queue.TryPush(task)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		title := "First"
+		edited, err := service.EditTask(first.ID, dto.EditTaskRequest{Title: &title})
+		require.NoError(t, err)
+		assert.Empty(t, edited.History)
+		assert.Equal(t, 2, service.queue.Len())
+	})
+
+	t.Run("reschedule", func(t *testing.T) {
+		delay := "1h"
+		edited, err := service.EditTask(first.ID, dto.EditTaskRequest{Delay: &delay})
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusScheduled, edited.Status)
+		require.NotNil(t, edited.RunAt)
+		assert.Equal(t, 1, service.queue.Len())
+		assert.Equal(t, 1, service.delayed.Len())
+
+		// Пустое значение возвращает задачу в очередь
+		runAt := ""
+		edited, err = service.EditTask(first.ID, dto.EditTaskRequest{RunAt: &runAt})
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusPending, edited.Status)
+		assert.Nil(t, edited.RunAt)
+		assert.Len(t, edited.History, 2)
+		assert.Eventually(t, func() bool {
+			return service.queue.Len() == 2 && service.delayed.Len() == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		empty, priority, runAt := " ", 42, "tomorrow"
+
+		_, err := service.EditTask(first.ID, dto.EditTaskRequest{Title: &empty})
+		assert.ErrorIs(t, err, ErrInvalidTaskUpdate)
+		_, err = service.EditTask(first.ID, dto.EditTaskRequest{Priority: &priority})
+		assert.ErrorIs(t, err, ErrInvalidPriority)
+		_, err = service.EditTask(first.ID, dto.EditTaskRequest{RunAt: &runAt})
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+		_, err = service.EditTask("abc", dto.EditTaskRequest{})
+		assert.ErrorIs(t, err, ErrInvalidTaskID)
+
+		// Некорректный запрос не трогает очередь
+		assert.Equal(t, 2, service.queue.Len())
+	})
+
+	t.Run("started", func(t *testing.T) {
+		title := "Too late"
+		_, err := service.EditTask(busy.ID, dto.EditTaskRequest{Title: &title})
+		assert.ErrorIs(t, err, ErrTaskNotEditable)
+
+		_, err = service.CancelTask(context.Background(), first.ID, "")
+		require.NoError(t, err)
+		_, err = service.EditTask(first.ID, dto.EditTaskRequest{Title: &title})
+		assert.ErrorIs(t, err, ErrTaskNotEditable)
+	})
+}
+
+func TestEditScheduledTask(t *testing.T) {
+	service, repo := newBlockingService(t)
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title: "Task", Description: "Description", Delay: "1h",
+	})
+	require.NoError(t, err)
+
+	// Перенос на ближайшее время запускает задачу
+	delay := "10ms"
+	edited, err := service.EditTask(task.ID, dto.EditTaskRequest{Delay: &delay})
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusScheduled, edited.Status)
+	assert.WithinDuration(t, time.Now().Add(10*time.Millisecond), *edited.RunAt, time.Second)
+
+	waitForStatus(t, repo, task.ID, model.StatusProcessing)
+	assert.Equal(t, 0, service.delayed.Len())
+}
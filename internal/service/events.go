@@ -0,0 +1,251 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// EventType is the kind of change an Event describes
+type EventType string
+
+const (
+	// EventStatus is published when a task is created or changes status
+	EventStatus EventType = "status"
+	// EventProgress is published when the executor of a task reports progress
+	EventProgress EventType = "progress"
+	// EventResult is published when a task reaches a terminal status
+	EventResult EventType = "result"
+)
+
+// IsValid reports whether t is one of the known event types
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventStatus, EventProgress, EventResult:
+		return true
+	default:
+		return false
+	}
+}
+
+// EventConfig holds the limits of the task event bus
+type EventConfig struct {
+	// HistorySize is how many recent events are kept for subscribers that
+	// resume from an earlier event
+	HistorySize int
+	// SubscriberBuffer is how many events a subscriber may fall behind before
+	// its subscription is closed
+	SubscriberBuffer int
+}
+
+// Event describes a change of a task
+type Event struct {
+	// ID increases with every event published by the service
+	ID   uint64
+	Type EventType
+	Time time.Time
+	// Task is a snapshot of the task after the change. Progress events only
+	// carry the ID, type, workflow, status and progress of the task.
+	Task *model.Task
+}
+
+// EventFilter selects the events a subscriber receives. Empty fields match
+// every event.
+type EventFilter struct {
+	TaskID     string
+	WorkflowID string
+	Types      []string
+	Statuses   []model.TaskStatus
+	Events     []EventType
+}
+
+// Match reports whether the event passes the filter
+func (f EventFilter) Match(event Event) bool {
+	task := event.Task
+	if f.TaskID != "" && task.ID != f.TaskID {
+		return false
+	}
+	if f.WorkflowID != "" && task.WorkflowID != f.WorkflowID {
+		return false
+	}
+	return matchAny(f.Types, task.Type) &&
+		matchAny(f.Statuses, task.Status) &&
+		matchAny(f.Events, event.Type)
+}
+
+// matchAny reports whether value is in values, or values is empty
+func matchAny[T comparable](values []T, value T) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives the events of an EventBus that pass its filter
+type Subscription struct {
+	// Replay holds the kept events after the ID the subscriber resumed from
+	Replay []Event
+	// LastID is the ID of the last event published before the subscription started
+	LastID uint64
+	// C delivers new events. It is closed when the subscriber falls too far
+	// behind or the bus shuts down; the subscriber may then resume from the
+	// last event it received.
+	C <-chan Event
+
+	events chan Event
+	filter EventFilter
+	bus    *EventBus
+	lagged bool
+}
+
+// Close stops the delivery of events
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Lagged reports whether C was closed because the subscriber fell behind,
+// rather than because the bus shut down. It is only meaningful once C is closed.
+func (s *Subscription) Lagged() bool {
+	return s.lagged
+}
+
+// EventBus fans task events out to subscribers and keeps the most recent
+// ones, so subscribers can resume after a reconnect without missing any
+type EventBus struct {
+	mu          sync.Mutex
+	config      EventConfig
+	lastID      uint64
+	history     []Event
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewEventBus(config EventConfig) *EventBus {
+	if config.SubscriberBuffer < 1 {
+		config.SubscriberBuffer = 1
+	}
+	return &EventBus{
+		config:      config,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the event an ID and delivers it to the matching subscribers
+func (b *EventBus) Publish(eventType EventType, task *model.Task) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now(), Task: task}
+
+	if b.config.HistorySize > 0 {
+		b.history = append(b.history, event)
+		if len(b.history) > b.config.HistorySize {
+			b.history = b.history[len(b.history)-b.config.HistorySize:]
+		}
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// A subscriber that can not keep up resumes from its last event
+			delete(b.subscribers, sub)
+			sub.lagged = true
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe starts delivering the events that pass the filter. Kept events
+// published after lastID are returned in the subscription's Replay; a lastID
+// of 0 replays nothing.
+func (b *EventBus) Subscribe(filter EventFilter, lastID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan Event, b.config.SubscriberBuffer)
+	sub := &Subscription{
+		LastID: b.lastID,
+		C:      events,
+		events: events,
+		filter: filter,
+		bus:    b,
+	}
+
+	if lastID > 0 {
+		start := sort.Search(len(b.history), func(i int) bool {
+			return b.history[i].ID > lastID
+		})
+		for _, event := range b.history[start:] {
+			if filter.Match(event) {
+				sub.Replay = append(sub.Replay, event)
+			}
+		}
+	}
+
+	if b.closed {
+		close(events)
+		return sub
+	}
+
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (b *EventBus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// Close ends every subscription and stops publishing
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// SubscribeEvents subscribes to the task events that pass the filter,
+// resuming after the event with ID lastID
+func (s *TaskService) SubscribeEvents(filter EventFilter, lastID uint64) *Subscription {
+	return s.events.Subscribe(filter, lastID)
+}
+
+// publish announces a change of the task to event subscribers
+func (s *TaskService) publish(task *model.Task) {
+	eventType := EventStatus
+	if task.Status.IsTerminal() {
+		eventType = EventResult
+	}
+
+	s.events.Publish(eventType, task.Clone())
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// nextEvent ждёт следующее событие подписки
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "no event received")
+		return Event{}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	t.Run("filter", func(t *testing.T) {
+		bus := NewEventBus(DefaultConfig().Events)
+		sub := bus.Subscribe(EventFilter{Statuses: []model.TaskStatus{model.StatusCompleted}}, 0)
+		defer sub.Close()
+
+		bus.Publish(EventStatus, &model.Task{ID: "1", Status: model.StatusProcessing})
+		bus.Publish(EventResult, &model.Task{ID: "1", Status: model.StatusCompleted})
+
+		event := nextEvent(t, sub)
+		assert.Equal(t, uint64(2), event.ID)
+		assert.Equal(t, EventResult, event.Type)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		bus := NewEventBus(EventConfig{HistorySize: 3, SubscriberBuffer: 8})
+		for i := 0; i < 5; i++ {
+			bus.Publish(EventStatus, &model.Task{ID: "1", Status: model.StatusPending})
+		}
+
+		// Хранятся только три последних события
+		sub := bus.Subscribe(EventFilter{}, 1)
+		defer sub.Close()
+		require.Len(t, sub.Replay, 3)
+		assert.Equal(t, uint64(3), sub.Replay[0].ID)
+		assert.Equal(t, uint64(5), sub.LastID)
+
+		sub = bus.Subscribe(EventFilter{}, 4)
+		defer sub.Close()
+		require.Len(t, sub.Replay, 1)
+		assert.Equal(t, uint64(5), sub.Replay[0].ID)
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		bus := NewEventBus(EventConfig{SubscriberBuffer: 1})
+		sub := bus.Subscribe(EventFilter{}, 0)
+
+		bus.Publish(EventStatus, &model.Task{ID: "1"})
+		bus.Publish(EventStatus, &model.Task{ID: "1"})
+
+		// Подписчик, не успевающий читать события, отключается
+		<-sub.C
+		_, ok := <-sub.C
+		assert.False(t, ok)
+		sub.Close()
+	})
+
+	t.Run("close", func(t *testing.T) {
+		bus := NewEventBus(DefaultConfig().Events)
+		sub := bus.Subscribe(EventFilter{}, 0)
+
+		bus.Close()
+		_, ok := <-sub.C
+		assert.False(t, ok)
+		sub.Close()
+	})
+}
+
+func TestTaskEvents(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+			ReportProgress(ctx, 50, "halfway", "")
+			return TextResult("done"), nil
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	sub := service.SubscribeEvents(EventFilter{}, 0)
+	defer sub.Close()
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+	require.NoError(t, err)
+
+	event := nextEvent(t, sub)
+	assert.Equal(t, EventStatus, event.Type)
+	assert.Equal(t, task.ID, event.Task.ID)
+	assert.Equal(t, model.StatusPending, event.Task.Status)
+
+	event = nextEvent(t, sub)
+	assert.Equal(t, EventStatus, event.Type)
+	assert.Equal(t, model.StatusProcessing, event.Task.Status)
+
+	event = nextEvent(t, sub)
+	assert.Equal(t, EventProgress, event.Type)
+	require.NotNil(t, event.Task.Progress)
+	assert.Equal(t, "halfway", event.Task.Progress.Stage)
+
+	event = nextEvent(t, sub)
+	assert.Equal(t, EventResult, event.Type)
+	assert.Equal(t, model.StatusCompleted, event.Task.Status)
+	assert.JSONEq(t, `"done"`, string(event.Task.Result))
+
+	// Переподключение с последнего полученного события повторяет пропущенные
+	resumed := service.SubscribeEvents(EventFilter{TaskID: task.ID}, 2)
+	defer resumed.Close()
+	require.Len(t, resumed.Replay, 2)
+	assert.Equal(t, EventResult, resumed.Replay[1].Type)
+}
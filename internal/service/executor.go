@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// DefaultTaskType is the task type served by the built-in simulated executor
+const DefaultTaskType = "default"
+
+var ErrExecutorNotFound = errors.New("no executor registered for task type")
+
+// TaskExecutor performs the actual work of a task and returns its result as JSON.
+// Implementations must return promptly once ctx is cancelled.
+type TaskExecutor interface {
+	Execute(ctx context.Context, task *model.Task) (json.RawMessage, error)
+}
+
+// PayloadValidator may be implemented by a TaskExecutor to reject malformed
+// payloads when a task is created rather than when it is executed
+type PayloadValidator interface {
+	ValidatePayload(payload json.RawMessage) error
+}
+
+// ExecutorFunc adapts an ordinary function to the TaskExecutor interface
+type ExecutorFunc func(ctx context.Context, task *model.Task) (json.RawMessage, error)
+
+// Execute calls f(ctx, task)
+func (f ExecutorFunc) Execute(ctx context.Context, task *model.Task) (json.RawMessage, error) {
+	return f(ctx, task)
+}
+
+// TextResult encodes a plain text result as a JSON string
+func TextResult(text string) json.RawMessage {
+	result, _ := json.Marshal(text)
+	return result
+}
+
+// ExecutorRegistry maps task types to the executors that handle them
+type ExecutorRegistry struct {
+	executors map[string]TaskExecutor
+	mu        sync.RWMutex
+}
+
+func NewExecutorRegistry() *ExecutorRegistry {
+	return &ExecutorRegistry{
+		executors: make(map[string]TaskExecutor),
+	}
+}
+
+// Register sets the executor for the given task type, replacing any previous one
+func (r *ExecutorRegistry) Register(taskType string, executor TaskExecutor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.executors[taskType] = executor
+}
+
+// Get returns the executor registered for the given task type
+func (r *ExecutorRegistry) Get(taskType string) (TaskExecutor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	executor, ok := r.executors[taskType]
+	return executor, ok
+}
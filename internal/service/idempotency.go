@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// MaxIdempotencyKeyLength is the longest accepted idempotency key
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used for a different request")
+)
+
+// createIdempotent creates the task unless the same request with the same key
+// created one within the idempotency window, in which case that task is returned
+func (s *TaskService) createIdempotent(req dto.CreateTaskRequest) (*model.Task, error) {
+	if len(req.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, errors.Wrapf(ErrInvalidIdempotencyKey, "key must not be longer than %d bytes",
+			MaxIdempotencyKeyLength)
+	}
+
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	task, err := s.repo.FindTaskByIdempotencyKey(req.IdempotencyKey)
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+	case err != nil:
+		return nil, errors.Wrap(err, "find task by idempotency key")
+	case time.Since(task.CreatedAt) < s.config.IdempotencyWindow:
+		if task.RequestHash != requestHash(req) {
+			return nil, ErrIdempotencyKeyReused
+		}
+
+		s.logger.Info("Task creation replayed",
+			zap.String("task_id", task.ID),
+			zap.String("idempotency_key", req.IdempotencyKey))
+		return s.withProgress(task), nil
+	}
+
+	return s.createTask(req)
+}
+
+// requestHash fingerprints a create request, ignoring the formatting of its payload
+func requestHash(req dto.CreateTaskRequest) string {
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, req.Payload); err == nil {
+		req.Payload = payload.Bytes()
+	}
+
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
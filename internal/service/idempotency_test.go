@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestIdempotentCreateTask(t *testing.T) {
+	repo := repository.NewTaskRepository()
+	service := newInstantService(t, repo, DefaultConfig())
+
+	req := dto.CreateTaskRequest{
+		Title:          "Task",
+		Description:    "Description",
+		Payload:        json.RawMessage(`{"a": 1, "b": [1, 2]}`),
+		IdempotencyKey: "key-1",
+	}
+
+	first, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+
+	// Повтор с тем же ключом возвращает исходную задачу, форматирование payload не важно
+	req.Payload = json.RawMessage(`{"a":1,"b":[1,2]}`)
+	second, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	tasks, err := repo.ListTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	// Тот же ключ с другим запросом
+	req.Title = "Other"
+	_, err = service.CreateTask(context.Background(), req)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// Другой ключ создаёт новую задачу
+	req.IdempotencyKey = "key-2"
+	third, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, third.ID)
+
+	req.IdempotencyKey = strings.Repeat("k", MaxIdempotencyKeyLength+1)
+	_, err = service.CreateTask(context.Background(), req)
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
+
+func TestIdempotencyWindow(t *testing.T) {
+	repo := repository.NewTaskRepository()
+	config := DefaultConfig()
+	config.IdempotencyWindow = 50 * time.Millisecond
+	service := newInstantService(t, repo, config)
+
+	req := dto.CreateTaskRequest{Title: "Task", Description: "Description", IdempotencyKey: "key"}
+	first, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+
+	// После окна ключ можно использовать снова, даже с другим запросом
+	time.Sleep(100 * time.Millisecond)
+	req.Title = "Other"
+	second, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// Теперь ключ относится к новой задаче
+	third, err := service.CreateTask(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, third.ID)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// Page sizes of task listings
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+var ErrInvalidListQuery = errors.New("invalid task list query")
+
+// TaskPage is one page of a task listing
+type TaskPage struct {
+	Tasks []*model.Task
+	// NextCursor continues the listing after this page. It is empty on the last page.
+	NextCursor string
+}
+
+// listCursor is the decoded form of a page cursor. It records the order it
+// was issued for, so it can not continue a listing in a different order.
+type listCursor struct {
+	Sort       repository.TaskSort `json:"s"`
+	Descending bool                `json:"d,omitempty"`
+	CreatedAt  int64               `json:"c"`
+	Priority   int                 `json:"p"`
+	ID         int64               `json:"i"`
+}
+
+// ListTasks returns a page of the tasks that pass the request's filters
+func (s *TaskService) ListTasks(req dto.ListTasksRequest) (*TaskPage, error) {
+	query, err := taskQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra task tells whether another page follows
+	limit := query.Limit
+	query.Limit++
+
+	tasks, err := s.repo.QueryTasks(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "query tasks")
+	}
+
+	page := &TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = encodeCursor(query, repository.CursorOf(page.Tasks[limit-1]))
+	}
+
+	for i, task := range page.Tasks {
+		page.Tasks[i] = s.withProgress(task)
+	}
+	return page, nil
+}
+
+// taskQuery validates a list request and turns it into a repository query
+func taskQuery(req dto.ListTasksRequest) (repository.TaskQuery, error) {
+	query := repository.TaskQuery{
+		Types: req.Types,
+		Sort:  repository.SortByCreatedAt,
+		Limit: DefaultListLimit,
+	}
+
+	for _, status := range req.Statuses {
+		if !model.TaskStatus(status).IsValid() {
+			return query, errors.Wrapf(ErrInvalidListQuery, "unknown status %q", status)
+		}
+		query.Statuses = append(query.Statuses, model.TaskStatus(status))
+	}
+
+	var err error
+	if query.CreatedAfter, err = parseListTime("created_after", req.CreatedAfter); err != nil {
+		return query, err
+	}
+	if query.CreatedBefore, err = parseListTime("created_before", req.CreatedBefore); err != nil {
+		return query, err
+	}
+
+	if req.Sort != "" {
+		query.Sort = repository.TaskSort(req.Sort)
+		if !query.Sort.IsValid() {
+			return query, errors.Wrapf(ErrInvalidListQuery, "unknown sort field %q", req.Sort)
+		}
+	}
+
+	switch req.Order {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, errors.Wrapf(ErrInvalidListQuery, "order must be asc or desc, got %q", req.Order)
+	}
+
+	switch {
+	case req.Limit < 0 || req.Limit > MaxListLimit:
+		return query, errors.Wrapf(ErrInvalidListQuery, "limit must be between 1 and %d", MaxListLimit)
+	case req.Limit > 0:
+		query.Limit = req.Limit
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(query, req.Cursor)
+		if err != nil {
+			return query, err
+		}
+		query.After = &after
+	}
+
+	return query, nil
+}
+
+func parseListTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidListQuery, "%s %q is not an RFC3339 time", name, value)
+	}
+	return &t, nil
+}
+
+// encodeCursor returns the opaque cursor of a position in the query's order
+func encodeCursor(query repository.TaskQuery, position repository.TaskCursor) string {
+	data, _ := json.Marshal(listCursor{
+		Sort:       query.Sort,
+		Descending: query.Descending,
+		CreatedAt:  position.CreatedAt.UnixNano(),
+		Priority:   position.Priority,
+		ID:         position.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor issued for the same order as the query
+func decodeCursor(query repository.TaskQuery, raw string) (repository.TaskCursor, error) {
+	var cursor listCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.ID <= 0 {
+		return repository.TaskCursor{}, errors.Wrap(ErrInvalidListQuery, "malformed cursor")
+	}
+
+	if cursor.Sort != query.Sort || cursor.Descending != query.Descending {
+		return repository.TaskCursor{}, errors.Wrap(ErrInvalidListQuery, "cursor belongs to a listing in a different order")
+	}
+
+	return repository.TaskCursor{
+		CreatedAt: time.Unix(0, cursor.CreatedAt).UTC(),
+		Priority:  cursor.Priority,
+		ID:        cursor.ID,
+	}, nil
+}
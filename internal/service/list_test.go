@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestListTasksPages(t *testing.T) {
+	repo := repository.NewTaskRepository()
+	service := NewTaskService(repo, setupTestLogger(), DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	addTask := func(taskType string) {
+		task := model.NewTask("Task", "", taskType, nil)
+		task.UpdateStatus(model.StatusCompleted)
+		_, err := repo.CreateTask(task)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 5; i++ {
+		addTask("default")
+	}
+
+	req := dto.ListTasksRequest{Order: "desc", Limit: 2}
+	var ids []string
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+
+		page, err := service.ListTasks(req)
+		require.NoError(t, err)
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+
+		// Задачи, созданные между страницами, не попадают в уже начатый обход
+		if pages == 0 {
+			addTask("default")
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"5", "4", "3", "2", "1"}, ids)
+
+	// Курсор нельзя использовать с другим порядком сортировки
+	page, err := service.ListTasks(dto.ListTasksRequest{Limit: 1})
+	require.NoError(t, err)
+	_, err = service.ListTasks(dto.ListTasksRequest{Order: "desc", Cursor: page.NextCursor})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+}
+
+func TestListTasksInvalidQuery(t *testing.T) {
+	service := NewTaskService(repository.NewTaskRepository(), setupTestLogger(), DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	for name, req := range map[string]dto.ListTasksRequest{
+		"status": {Statuses: []string{"unknown"}},
+		"time":   {CreatedAfter: "yesterday"},
+		"sort":   {Sort: "title"},
+		"order":  {Order: "up"},
+		"limit":  {Limit: MaxListLimit + 1},
+		"cursor": {Cursor: "not-a-cursor"},
+	} {
+		_, err := service.ListTasks(req)
+		assert.ErrorIs(t, err, ErrInvalidListQuery, name)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// ProgressReporter lets an executor tell how far along a task is.
+// Percent is clamped to the range 0-100 and a report with a percent that is
+// not a number or infinite is dropped; stage and message are free text.
+type ProgressReporter interface {
+	Report(percent float64, stage, message string)
+}
+
+type progressKey struct{}
+
+// ProgressFromContext returns the reporter of the task running with ctx.
+// Outside of a task it returns a reporter that discards everything.
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	if reporter, ok := ctx.Value(progressKey{}).(ProgressReporter); ok {
+		return reporter
+	}
+	return discardProgress{}
+}
+
+// ReportProgress reports progress through the reporter of ctx
+func ReportProgress(ctx context.Context, percent float64, stage, message string) {
+	ProgressFromContext(ctx).Report(percent, stage, message)
+}
+
+type discardProgress struct{}
+
+func (discardProgress) Report(float64, string, string) {}
+
+// progressReporter records the progress of a running task. The values are kept
+// in memory while the task runs and stored with the task when it finishes.
+type progressReporter struct {
+	service *TaskService
+	// task is only read for the fields that do not change while it runs
+	task    *model.Task
+	running *runningTask
+	started time.Time
+}
+
+func (r *progressReporter) Report(percent float64, stage, message string) {
+	// NaN would pass the clamp and break the JSON encoding of the task
+	if math.IsNaN(percent) || math.IsInf(percent, 0) {
+		return
+	}
+	percent = min(max(percent, 0), 100)
+	now := time.Now()
+
+	progress := &model.TaskProgress{
+		Percent:   percent,
+		Stage:     stage,
+		Message:   message,
+		UpdatedAt: now,
+		ETA:       estimateETA(r.started, now, percent),
+	}
+
+	r.service.runningMu.Lock()
+	current := r.service.running[r.task.ID] == r.running
+	if current {
+		r.running.progress = progress
+	}
+	r.service.runningMu.Unlock()
+
+	// An executor left behind after a timeout may still report
+	if !current {
+		return
+	}
+
+	published := *progress
+	r.service.events.Publish(EventProgress, &model.Task{
+		ID:         r.task.ID,
+		Type:       r.task.Type,
+		WorkflowID: r.task.WorkflowID,
+		Status:     model.StatusProcessing,
+		Progress:   &published,
+	})
+}
+
+// estimateETA extrapolates when a task finishes from the rate its progress
+// has grown since it started. It returns nil until there is a rate to go by.
+func estimateETA(started, now time.Time, percent float64) *time.Time {
+	if percent <= 0 || percent >= 100 {
+		return nil
+	}
+
+	elapsed := now.Sub(started)
+	remaining := time.Duration(float64(elapsed) * (100 - percent) / percent)
+	eta := now.Add(remaining)
+	return &eta
+}
+
+// withProgress returns the task with the latest progress of its executor,
+// if a worker of this service is running it
+func (s *TaskService) withProgress(task *model.Task) *model.Task {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	running, ok := s.running[task.ID]
+	if !ok || running.progress == nil {
+		return task
+	}
+
+	progress := *running.progress
+	withProgress := *task
+	withProgress.Progress = &progress
+	return &withProgress
+}
+
+// recordProgress copies the last progress of a running task onto the task,
+// so it is stored along with the outcome of the attempt
+func (s *TaskService) recordProgress(task *model.Task) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	running, ok := s.running[task.ID]
+	if !ok || running.progress == nil {
+		return
+	}
+
+	progress := *running.progress
+	progress.ETA = nil
+	task.Progress = &progress
+}
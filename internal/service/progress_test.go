@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestTaskProgress(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	reported := make(chan struct{})
+	release := make(chan struct{})
+
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+			time.Sleep(20 * time.Millisecond)
+			ReportProgress(ctx, 40, "upload", "2 of 5 files")
+			// Нечисловой прогресс отбрасывается
+			ReportProgress(ctx, math.NaN(), "nan", "")
+			ReportProgress(ctx, math.Inf(1), "inf", "")
+			close(reported)
+			<-release
+			return TextResult("done"), nil
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+	require.NoError(t, err)
+	<-reported
+
+	got, err := service.GetTask(task.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.Progress)
+	assert.Equal(t, 40.0, got.Progress.Percent)
+	assert.Equal(t, "upload", got.Progress.Stage)
+	assert.Equal(t, "2 of 5 files", got.Progress.Message)
+	require.NotNil(t, got.Progress.ETA)
+	assert.True(t, got.Progress.ETA.After(got.Progress.UpdatedAt))
+
+	// Прогресс хранится отдельно и не меняет задачу в репозитории
+	stored, err := repo.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Progress)
+
+	close(release)
+
+	// После завершения сохраняется последний прогресс без оценки времени
+	task = waitForStatus(t, repo, task.ID, model.StatusCompleted)
+	require.NotNil(t, task.Progress)
+	assert.Equal(t, 100.0, task.Progress.Percent)
+	assert.Equal(t, "upload", task.Progress.Stage)
+	assert.Nil(t, task.Progress.ETA)
+}
+
+func TestEstimateETA(t *testing.T) {
+	started := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	now := started.Add(time.Minute)
+
+	// За минуту выполнено 25%, значит осталось ещё три минуты
+	eta := estimateETA(started, now, 25)
+	require.NotNil(t, eta)
+	assert.Equal(t, now.Add(3*time.Minute), *eta)
+
+	assert.Nil(t, estimateETA(started, now, 0))
+	assert.Nil(t, estimateETA(started, now, 100))
+
+	// Вне задачи отчёт о прогрессе ничего не делает
+	ReportProgress(context.Background(), 50, "stage", "message")
+}
+
+func TestSimulateProcessingShortDelay(t *testing.T) {
+	// Задержка короче числа шагов не должна ронять тикер
+	service := &TaskService{processingDelay: 5 * time.Nanosecond}
+	result, err := service.simulateProcessing(context.Background(), &model.Task{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result)
+}
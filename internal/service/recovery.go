@@ -0,0 +1,162 @@
+package service
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// StalePolicy decides what happens to a task that was left in processing by a
+// worker that is no longer running
+type StalePolicy string
+
+const (
+	// StalePolicyRequeue puts the task back in the queue to run again
+	StalePolicyRequeue StalePolicy = "requeue"
+	// StalePolicyFail marks the task as failed
+	StalePolicyFail StalePolicy = "fail"
+)
+
+var ErrWorkerLost = errors.New("worker stopped before the task finished")
+
+// RecoveryConfig holds configuration of startup recovery and the stale task reaper
+type RecoveryConfig struct {
+	// Enabled turns on the stale policy for tasks left in processing, at
+	// startup and by the reaper. It only makes sense with a durable repository.
+	Enabled     bool
+	StalePolicy StalePolicy
+	// ReapInterval is how often the reaper looks for stale tasks, 0 disables it
+	ReapInterval time.Duration
+	// StaleAfter is how long a task may sit in processing without a live
+	// worker before the reaper considers it stale
+	StaleAfter time.Duration
+}
+
+// recoverTasks is run once at startup, before the service takes any request,
+// so no task it finds can also be queued by CreateTask. Pending tasks are
+// queued again in creation order, scheduled tasks and tasks waiting for a
+// retry go back to the delay queue and blocked tasks wait for their
+// dependencies again, whether or not recovery is enabled, since nothing else
+// would ever run them. With recovery enabled, tasks left in processing by the
+// previous run are handled according to the stale policy. Pending tasks are
+// handed to the workers through the delay queue as well, so startup does not
+// wait for queue space.
+func (s *TaskService) recoverTasks() {
+	tasks, err := s.repo.ListTasks()
+	if err != nil {
+		s.logger.Error("Failed to list tasks for recovery", err)
+		return
+	}
+
+	pending, delayed, blocked := 0, 0, 0
+	for _, task := range tasks {
+		switch task.Status {
+		case model.StatusProcessing:
+			if s.config.Recovery.Enabled && s.recoverStaleTask(task) {
+				s.delayed.Add(task.ID, task.CreatedAt)
+				pending++
+			}
+		case model.StatusPending:
+			if task.NextRetryAt != nil {
+				s.delayed.Add(task.ID, *task.NextRetryAt)
+				delayed++
+				continue
+			}
+			// Due at creation, so pending tasks are released in creation order
+			s.delayed.Add(task.ID, task.CreatedAt)
+			pending++
+		case model.StatusScheduled:
+			if task.RunAt != nil {
+				s.delayed.Add(task.ID, *task.RunAt)
+				delayed++
+			}
+		case model.StatusBlocked:
+			s.dependencies.watch(task)
+			blocked++
+		}
+	}
+
+	s.logger.Info("Recovered tasks",
+		zap.Int("pending", pending),
+		zap.Int("delayed", delayed),
+		zap.Int("blocked", blocked))
+}
+
+// recoverStaleTask applies the stale policy to a task that was abandoned in
+// processing. It returns true if the task has to be queued again.
+func (s *TaskService) recoverStaleTask(task *model.Task) bool {
+	if s.config.Recovery.StalePolicy == StalePolicyFail {
+		s.failTask(task, ErrWorkerLost)
+		return false
+	}
+
+	task.Status = model.StatusPending
+	task.StartedAt = nil
+	task.DurationStr = ""
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to requeue stale task", err,
+			zap.String("task_id", task.ID))
+		return false
+	}
+
+	s.logger.Info("Stale task requeued", zap.String("task_id", task.ID))
+	return true
+}
+
+// reapStaleTasks periodically looks for tasks in processing that no worker of
+// this service is running and applies the stale policy to them
+func (s *TaskService) reapStaleTasks() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Recovery.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-ticker.C:
+			s.reapOnce()
+		}
+	}
+}
+
+func (s *TaskService) reapOnce() {
+	tasks, err := s.repo.ListTasks()
+	if err != nil {
+		s.logger.Error("Failed to list tasks for reaping", err)
+		return
+	}
+
+	for _, task := range tasks {
+		if task.Status != model.StatusProcessing || s.isRunning(task.ID) {
+			continue
+		}
+		if task.StartedAt != nil && time.Since(*task.StartedAt) < s.config.Recovery.StaleAfter {
+			continue
+		}
+
+		s.logger.Warn("Reaping stale task", zap.String("task_id", task.ID))
+		if s.recoverStaleTask(task) {
+			s.enqueue(task)
+		}
+	}
+}
+
+// enqueue hands the task to the worker pool without waiting for queue space.
+// While the queue and its overflow buffer are full, the task waits in the
+// delay queue and is tried again once the workers may have caught up.
+func (s *TaskService) enqueue(task *model.Task) {
+	if !s.queue.Reserve(1) {
+		retryAt := time.Now().Add(s.RetryAfter())
+		s.logger.Warn("Task queue is full, postponing task",
+			zap.String("task_id", task.ID),
+			zap.Time("retry_at", retryAt))
+		s.delayed.Add(task.ID, retryAt)
+		return
+	}
+	s.queue.PushReserved(task)
+}
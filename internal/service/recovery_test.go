@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// waitForStatus ждёт, пока задача в репозитории не перейдёт в нужный статус
+func waitForStatus(t *testing.T, repo repository.TaskRepositoryInterface, id string, status model.TaskStatus) *model.Task {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		task, err := repo.GetTask(id)
+		require.NoError(t, err)
+		if task.Status == status {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("task %s did not reach status %s", id, status)
+	return nil
+}
+
+// createStoredTask сохраняет задачу в репозиторий в обход сервиса, как будто она осталась от прошлого запуска
+func createStoredTask(t *testing.T, repo repository.TaskRepositoryInterface, status model.TaskStatus) *model.Task {
+	t.Helper()
+
+	task, err := repo.CreateTask(model.NewTask("Task", "Description", DefaultTaskType, nil))
+	require.NoError(t, err)
+
+	if status != model.StatusPending {
+		task.UpdateStatus(status)
+		_, err = repo.UpdateTask(task)
+		require.NoError(t, err)
+	}
+
+	return task
+}
+
+func TestRecoverTasks(t *testing.T) {
+	t.Run("requeue", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		pending := createStoredTask(t, repo, model.StatusPending)
+		stale := createStoredTask(t, repo, model.StatusProcessing)
+
+		config := DefaultConfig()
+		config.Recovery.Enabled = true
+		config.Executors = map[string]TaskExecutor{
+			DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+				return TextResult("done"), nil
+			}),
+		}
+		service := NewTaskService(repo, setupTestLogger(), config)
+		defer service.Shutdown(context.Background())
+
+		waitForStatus(t, repo, pending.ID, model.StatusCompleted)
+		waitForStatus(t, repo, stale.ID, model.StatusCompleted)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		stale := createStoredTask(t, repo, model.StatusProcessing)
+
+		config := DefaultConfig()
+		config.Recovery.Enabled = true
+		config.Recovery.StalePolicy = StalePolicyFail
+		service := NewTaskService(repo, setupTestLogger(), config)
+		defer service.Shutdown(context.Background())
+
+		// Восстановление завершается до того, как NewTaskService вернёт сервис
+		task, err := repo.GetTask(stale.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusFailed, task.Status)
+		assert.Equal(t, ErrWorkerLost.Error(), task.Error)
+	})
+
+	t.Run("once", func(t *testing.T) {
+		repo := repository.NewTaskRepository()
+		stored := createStoredTask(t, repo, model.StatusPending)
+
+		var runs atomic.Int32
+		config := DefaultConfig()
+		config.Recovery.Enabled = true
+		config.Executors = map[string]TaskExecutor{
+			DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+				runs.Add(1)
+				return TextResult("done"), nil
+			}),
+		}
+		service := NewTaskService(repo, setupTestLogger(), config)
+		defer service.Shutdown(context.Background())
+
+		// Задачи, созданные сразу после запуска, не попадают в очередь второй раз
+		created, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+		require.NoError(t, err)
+
+		waitForStatus(t, repo, stored.ID, model.StatusCompleted)
+		waitForStatus(t, repo, created.ID, model.StatusCompleted)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(2), runs.Load())
+	})
+}
+
+func TestReapStaleTasks(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	config := DefaultConfig()
+	config.Recovery.Enabled = true
+	config.Recovery.StalePolicy = StalePolicyFail
+	config.Recovery.ReapInterval = 20 * time.Millisecond
+	config.Recovery.StaleAfter = 0
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	// Задача в статусе processing, которую не обрабатывает ни один воркер
+	orphan := createStoredTask(t, repo, model.StatusProcessing)
+
+	task := waitForStatus(t, repo, orphan.ID, model.StatusFailed)
+	assert.Equal(t, ErrWorkerLost.Error(), task.Error)
+}
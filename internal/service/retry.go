@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+var (
+	ErrInvalidRetryPolicy  = errors.New("invalid retry policy")
+	ErrTaskNotDeadLettered = errors.New("task is not in the dead letter queue")
+)
+
+// RetryConfig holds the server-wide retry defaults and limits
+type RetryConfig struct {
+	// DefaultMaxAttempts is used when a task does not set max_attempts
+	DefaultMaxAttempts int
+	// MaxAttempts is the largest max_attempts a task may ask for
+	MaxAttempts int
+	Backoff     model.BackoffPolicy
+}
+
+// permanentError marks an executor error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an executor error so that the task fails immediately
+// instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var perr *permanentError
+	return errors.As(err, &perr)
+}
+
+// retryPolicy resolves the attempts limit and backoff of a new task from the
+// request and the server defaults
+func (s *TaskService) retryPolicy(req dto.CreateTaskRequest) (int, model.BackoffPolicy, error) {
+	defaults := s.config.Retry
+
+	maxAttempts := req.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaults.DefaultMaxAttempts
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if req.MaxAttempts < 0 || (defaults.MaxAttempts > 0 && maxAttempts > defaults.MaxAttempts) {
+		return 0, model.BackoffPolicy{}, errors.Wrapf(ErrInvalidRetryPolicy,
+			"max_attempts must be between 1 and %d", defaults.MaxAttempts)
+	}
+
+	backoff := defaults.Backoff
+	if req.Backoff == nil {
+		return maxAttempts, backoff, nil
+	}
+
+	var err error
+	if req.Backoff.Initial != "" {
+		if backoff.Initial, err = time.ParseDuration(req.Backoff.Initial); err != nil || backoff.Initial < 0 {
+			return 0, model.BackoffPolicy{}, errors.Wrap(ErrInvalidRetryPolicy, "invalid backoff initial delay")
+		}
+	}
+	if req.Backoff.Max != "" {
+		if backoff.Max, err = time.ParseDuration(req.Backoff.Max); err != nil || backoff.Max < 0 {
+			return 0, model.BackoffPolicy{}, errors.Wrap(ErrInvalidRetryPolicy, "invalid backoff max delay")
+		}
+	}
+	if req.Backoff.Multiplier != 0 {
+		if req.Backoff.Multiplier < 1 {
+			return 0, model.BackoffPolicy{}, errors.Wrap(ErrInvalidRetryPolicy, "backoff multiplier must be at least 1")
+		}
+		backoff.Multiplier = req.Backoff.Multiplier
+	}
+	if req.Backoff.Jitter != 0 {
+		if req.Backoff.Jitter < 0 || req.Backoff.Jitter > 1 {
+			return 0, model.BackoffPolicy{}, errors.Wrap(ErrInvalidRetryPolicy, "backoff jitter must be between 0 and 1")
+		}
+		backoff.Jitter = req.Backoff.Jitter
+	}
+
+	return maxAttempts, backoff, nil
+}
+
+// backoffDelay returns how long to wait before the attempt that follows attempt n
+func backoffDelay(policy model.BackoffPolicy, attempt int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(policy.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if policy.Max > 0 && delay > float64(policy.Max) {
+		delay = float64(policy.Max)
+	}
+
+	if policy.Jitter > 0 {
+		delay *= 1 + policy.Jitter*(2*rand.Float64()-1) //nolint:gosec
+	}
+
+	return time.Duration(delay)
+}
+
+// handleFailure records a failed attempt and either schedules the next one or
+// gives up. Tasks that were allowed more than one attempt end in the dead
+// letter queue. Tasks with a single attempt, the default, simply fail: they
+// never retried, so there is nothing to redrive them from.
+func (s *TaskService) handleFailure(task *model.Task, taskErr error) {
+	task.AttemptLog = append(task.AttemptLog, model.AttemptError{
+		Attempt:  task.Attempts,
+		Error:    taskErr.Error(),
+		FailedAt: time.Now(),
+	})
+
+	if IsPermanent(taskErr) || task.Attempts >= task.MaxAttempts {
+		if task.MaxAttempts > 1 {
+			s.deadLetterTask(task, taskErr)
+		} else {
+			s.failTask(task, taskErr)
+		}
+		return
+	}
+
+	delay := backoffDelay(task.Backoff, task.Attempts)
+	nextRetryAt := time.Now().Add(delay)
+
+	task.Status = model.StatusPending
+	task.Error = taskErr.Error()
+	task.NextRetryAt = &nextRetryAt
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return
+	}
+
+	s.logger.Info("Task attempt failed, retrying",
+		zap.String("task_id", task.ID),
+		zap.Int("attempt", task.Attempts),
+		zap.Int("max_attempts", task.MaxAttempts),
+		zap.Duration("delay", delay),
+		zap.String("error", task.Error))
+
+	s.delayed.Add(task.ID, nextRetryAt)
+}
+
+// deadLetterTask moves a task that ran out of attempts to the dead letter queue
+func (s *TaskService) deadLetterTask(task *model.Task, taskErr error) {
+	task.UpdateStatus(model.StatusDeadLetter)
+	task.Error = taskErr.Error()
+	task.FailureReason = failureReason(taskErr)
+	task.NextRetryAt = nil
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return
+	}
+
+	s.logger.Warn("Task moved to dead letter queue",
+		zap.String("task_id", task.ID),
+		zap.Int("attempts", task.Attempts),
+		zap.String("error", task.Error))
+}
+
+// ListDeadLetterTasks returns the tasks that ran out of attempts
+func (s *TaskService) ListDeadLetterTasks() ([]*model.Task, error) {
+	tasks, err := s.repo.ListTasks()
+	if err != nil {
+		return nil, errors.Wrap(err, "list tasks")
+	}
+
+	deadLetter := make([]*model.Task, 0)
+	for _, task := range tasks {
+		if task.Status == model.StatusDeadLetter {
+			deadLetter = append(deadLetter, task)
+		}
+	}
+	return deadLetter, nil
+}
+
+// RedriveTask gives a dead-lettered task a fresh set of attempts and queues it again.
+// The errors of earlier attempts are kept.
+func (s *TaskService) RedriveTask(ctx context.Context, id string) (*model.Task, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidTaskID
+	}
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get task")
+	}
+
+	if task.Status != model.StatusDeadLetter {
+		return nil, ErrTaskNotDeadLettered
+	}
+
+	// Admit the task before saving it as pending, so a full queue leaves it in
+	// the dead letter queue instead of pending without being queued
+	if err := s.admit(1); err != nil {
+		return nil, err
+	}
+
+	task.Status = model.StatusPending
+	task.Attempts = 0
+	task.Error = ""
+	task.FailureReason = ""
+	task.StartedAt = nil
+	task.CompletedAt = nil
+	task.DurationStr = ""
+	task.NextRetryAt = nil
+	if err := s.updateTask(task); err != nil {
+		s.queue.Unreserve(1)
+		return nil, errors.Wrap(err, "update task")
+	}
+
+	s.logger.Info("Task redriven from dead letter queue", zap.String("task_id", task.ID))
+	s.queue.PushReserved(task)
+
+	return task, nil
+}
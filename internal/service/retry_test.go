@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	policy := model.BackoffPolicy{
+		Initial:    time.Second,
+		Multiplier: 2,
+		Max:        5 * time.Second,
+	}
+
+	assert.Equal(t, time.Second, backoffDelay(policy, 1))
+	assert.Equal(t, 2*time.Second, backoffDelay(policy, 2))
+	assert.Equal(t, 4*time.Second, backoffDelay(policy, 3))
+	assert.Equal(t, 5*time.Second, backoffDelay(policy, 4))
+
+	// С джиттером задержка остаётся в пределах ±jitter
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := backoffDelay(policy, 1)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	var calls atomic.Int32
+	var succeed atomic.Bool
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+			calls.Add(1)
+			if succeed.Load() {
+				return TextResult("done"), nil
+			}
+			return nil, errors.New("temporary failure")
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		MaxAttempts: 3,
+		Backoff:     &dto.BackoffPolicy{Initial: "10ms", Jitter: 0.1},
+	})
+	require.NoError(t, err)
+
+	task = waitForStatus(t, repo, task.ID, model.StatusDeadLetter)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 3, task.Attempts)
+	require.Len(t, task.AttemptLog, 3)
+	assert.Equal(t, "temporary failure", task.AttemptLog[2].Error)
+
+	deadLetter, err := service.ListDeadLetterTasks()
+	require.NoError(t, err)
+	assert.Len(t, deadLetter, 1)
+
+	// После повторной отправки задача получает новый набор попыток
+	succeed.Store(true)
+	_, err = service.RedriveTask(context.Background(), task.ID)
+	require.NoError(t, err)
+
+	task = waitForStatus(t, repo, task.ID, model.StatusCompleted)
+	assert.Equal(t, 1, task.Attempts)
+	assert.Len(t, task.AttemptLog, 3)
+
+	_, err = service.RedriveTask(context.Background(), task.ID)
+	assert.ErrorIs(t, err, ErrTaskNotDeadLettered)
+}
+
+func TestPermanentError(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+			return nil, Permanent(errors.New("bad input"))
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		MaxAttempts: 5,
+	})
+	require.NoError(t, err)
+
+	task = waitForStatus(t, repo, task.ID, model.StatusDeadLetter)
+	assert.Equal(t, 1, task.Attempts)
+}
+
+func TestSingleAttemptFails(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+			return nil, errors.New("temporary failure")
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+	require.NoError(t, err)
+
+	// Задача без повторов завершается ошибкой и не попадает в очередь недоставленных
+	task = waitForStatus(t, repo, task.ID, model.StatusFailed)
+	assert.Equal(t, 1, task.Attempts)
+	require.Len(t, task.AttemptLog, 1)
+
+	deadLetter, err := service.ListDeadLetterTasks()
+	require.NoError(t, err)
+	assert.Empty(t, deadLetter)
+
+	_, err = service.RedriveTask(context.Background(), task.ID)
+	assert.ErrorIs(t, err, ErrTaskNotDeadLettered)
+}
+
+func TestInvalidRetryPolicy(t *testing.T) {
+	service := NewTaskService(repository.NewTaskRepository(), setupTestLogger(), DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	_, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		MaxAttempts: 100,
+	})
+	assert.ErrorIs(t, err, ErrInvalidRetryPolicy)
+
+	_, err = service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		Backoff:     &dto.BackoffPolicy{Initial: "soon"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRetryPolicy)
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+var (
+	ErrInvalidScheduleID      = errors.New("invalid schedule ID format")
+	ErrInvalidCronExpression  = errors.New("invalid cron expression")
+	ErrInvalidTimezone        = errors.New("invalid timezone")
+	ErrInvalidMissedRunPolicy = errors.New("invalid missed run policy")
+	ErrInvalidTaskTemplate    = errors.New("invalid task template")
+)
+
+// maxScheduleWait bounds how long the schedule loop sleeps, so that it notices
+// wall clock changes
+const maxScheduleWait = time.Minute
+
+// ScheduleConfig holds configuration of recurring schedules
+type ScheduleConfig struct {
+	// MissedRunGrace is how late a run may be noticed and still count as on
+	// time rather than missed
+	MissedRunGrace time.Duration
+	// MaxCatchUpRuns limits how many tasks catch_up_all starts at once, the
+	// oldest missed runs beyond it are dropped. 0 means no limit.
+	MaxCatchUpRuns int
+}
+
+// TaskCreator creates the tasks that schedules spawn
+type TaskCreator interface {
+	CreateTask(ctx context.Context, req dto.CreateTaskRequest) (*model.Task, error)
+	ValidateTaskRequest(req dto.CreateTaskRequest) error
+	// RetryAfter suggests when to try again after ErrQueueFull
+	RetryAfter() time.Duration
+}
+
+type ScheduleServiceInterface interface {
+	CreateSchedule(req dto.CreateScheduleRequest) (*model.Schedule, error)
+	ListSchedules() ([]*model.Schedule, error)
+	GetSchedule(id string) (*model.Schedule, error)
+	DeleteSchedule(id string) error
+	PauseSchedule(id string) (*model.Schedule, error)
+	ResumeSchedule(id string) (*model.Schedule, error)
+	Shutdown(ctx context.Context) error
+}
+
+// ScheduleService creates a task from a schedule's template every time its
+// cron expression fires
+type ScheduleService struct {
+	repo   repository.ScheduleRepositoryInterface
+	tasks  TaskCreator
+	logger *logger.Logger
+	config ScheduleConfig
+	now    func() time.Time
+	// mu serialises storing the outcome of firing with changes made through the API
+	mu           sync.Mutex
+	wake         chan struct{}
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	shutdownChan chan struct{}
+}
+
+func NewScheduleService(repo repository.ScheduleRepositoryInterface, tasks TaskCreator, logger *logger.Logger, config ScheduleConfig) *ScheduleService {
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &ScheduleService{
+		repo:         repo,
+		tasks:        tasks,
+		logger:       logger,
+		config:       config,
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+		ctx:          ctx,
+		cancel:       cancel,
+		shutdownChan: make(chan struct{}),
+	}
+
+	service.wg.Add(1)
+	go service.run()
+
+	return service
+}
+
+// cronSchedule parses a standard five field cron expression (or a descriptor
+// such as @hourly) and the timezone it is evaluated in
+func cronSchedule(expr, timezone string) (cron.Schedule, *time.Location, error) {
+	spec, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidCronExpression, err.Error())
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, errors.Wrapf(ErrInvalidTimezone, "%q", timezone)
+	}
+
+	return spec, loc, nil
+}
+
+func (s *ScheduleService) CreateSchedule(req dto.CreateScheduleRequest) (*model.Schedule, error) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	spec, loc, err := cronSchedule(req.Cron, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := model.MissedRunPolicy(req.MissedRunPolicy)
+	switch policy {
+	case "":
+		policy = model.MissedRunSkip
+	case model.MissedRunSkip, model.MissedRunCatchUpOnce, model.MissedRunCatchUpAll:
+	default:
+		return nil, errors.Wrapf(ErrInvalidMissedRunPolicy, "%q", req.MissedRunPolicy)
+	}
+
+	if req.Task.Title == "" || req.Task.Description == "" {
+		return nil, errors.Wrap(ErrInvalidTaskTemplate, "title and description are required")
+	}
+	if err := s.tasks.ValidateTaskRequest(taskRequest(req.Task)); err != nil {
+		return nil, errors.Wrap(ErrInvalidTaskTemplate, err.Error())
+	}
+
+	now := s.now()
+	next := spec.Next(now.In(loc))
+	schedule := &model.Schedule{
+		Name:            req.Name,
+		Cron:            req.Cron,
+		Timezone:        timezone,
+		Template:        req.Task,
+		MissedRunPolicy: policy,
+		CreatedAt:       now,
+		NextRunAt:       &next,
+	}
+
+	s.mu.Lock()
+	schedule, err = s.repo.CreateSchedule(schedule)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "create schedule")
+	}
+
+	s.logger.Info("Schedule created",
+		zap.String("schedule_id", schedule.ID),
+		zap.String("cron", schedule.Cron),
+		zap.Time("next_run_at", next))
+
+	signal(s.wake)
+	return schedule, nil
+}
+
+func (s *ScheduleService) ListSchedules() ([]*model.Schedule, error) {
+	return s.repo.ListSchedules() //nolint:wrapcheck
+}
+
+func (s *ScheduleService) GetSchedule(id string) (*model.Schedule, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidScheduleID
+	}
+
+	schedule, err := s.repo.GetSchedule(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get schedule")
+	}
+	return schedule, nil
+}
+
+func (s *ScheduleService) DeleteSchedule(id string) error {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return ErrInvalidScheduleID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.repo.DeleteSchedule(id); err != nil {
+		return errors.Wrap(err, "delete schedule")
+	}
+
+	s.logger.Info("Schedule deleted", zap.String("schedule_id", id))
+	return nil
+}
+
+// PauseSchedule stops the schedule from firing until it is resumed
+func (s *ScheduleService) PauseSchedule(id string) (*model.Schedule, error) {
+	return s.setPaused(id, true)
+}
+
+// ResumeSchedule lets a paused schedule fire again. Runs that fell due while
+// it was paused are not caught up.
+func (s *ScheduleService) ResumeSchedule(id string) (*model.Schedule, error) {
+	return s.setPaused(id, false)
+}
+
+func (s *ScheduleService) setPaused(id string, paused bool) (*model.Schedule, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidScheduleID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, err := s.repo.GetSchedule(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "get schedule")
+	}
+
+	if schedule.Paused == paused {
+		return schedule, nil
+	}
+
+	schedule.Paused = paused
+	schedule.NextRunAt = nil
+	if !paused {
+		spec, loc, err := cronSchedule(schedule.Cron, schedule.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		next := spec.Next(s.now().In(loc))
+		schedule.NextRunAt = &next
+	}
+
+	if _, err := s.repo.UpdateSchedule(schedule); err != nil {
+		return nil, errors.Wrap(err, "update schedule")
+	}
+
+	s.logger.Info("Schedule paused state changed",
+		zap.String("schedule_id", schedule.ID),
+		zap.Bool("paused", paused))
+
+	signal(s.wake)
+	return schedule, nil
+}
+
+// run fires schedules as they fall due until Shutdown is called
+func (s *ScheduleService) run() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		wait := s.fireDue(s.ctx)
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// fireDue fires every schedule whose next run has come and returns how long
+// to wait for the next one
+func (s *ScheduleService) fireDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	schedules, err := s.repo.ListSchedules()
+	s.mu.Unlock()
+	if err != nil {
+		s.logger.Error("Failed to list schedules", err)
+		return maxScheduleWait
+	}
+
+	now := s.now()
+	wait := maxScheduleWait
+	for _, schedule := range schedules {
+		if schedule.Paused || schedule.NextRunAt == nil {
+			continue
+		}
+
+		if !schedule.NextRunAt.After(now) {
+			if retry := s.fire(ctx, schedule, now); retry > 0 && retry < wait {
+				wait = retry
+			}
+		}
+
+		if schedule.NextRunAt != nil {
+			if until := schedule.NextRunAt.Sub(now); until > 0 && until < wait {
+				wait = until
+			}
+		}
+	}
+
+	return wait
+}
+
+// fire starts the tasks for the runs of the schedule that are due at now,
+// applying its missed run policy, and moves it on to its next run.
+// A run turned away by a full queue stays due and fire returns how long to
+// wait before retrying it; a retry that comes too late counts as a missed
+// run under the schedule's policy. A run interrupted by shutdown stays due as well.
+func (s *ScheduleService) fire(ctx context.Context, schedule *model.Schedule, now time.Time) time.Duration {
+	spec, loc, err := cronSchedule(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		s.logger.Error("Failed to parse schedule", err, zap.String("schedule_id", schedule.ID))
+		return 0
+	}
+
+	runs, dropped := s.dueRuns(schedule, spec, loc, now)
+	if dropped > 0 {
+		s.logger.Warn("Schedule dropped missed runs",
+			zap.String("schedule_id", schedule.ID),
+			zap.Int("dropped", dropped),
+			zap.String("policy", string(schedule.MissedRunPolicy)))
+	}
+
+	firedFrom := *schedule.NextRunAt
+	for _, runAt := range runs {
+		task, err := s.tasks.CreateTask(ctx, taskRequest(schedule.Template))
+		if errors.Is(err, ErrQueueFull) {
+			retry := s.tasks.RetryAfter()
+			s.logger.Warn("Task queue is full, deferring scheduled run",
+				zap.String("schedule_id", schedule.ID),
+				zap.Time("run_at", runAt),
+				zap.Duration("retry_after", retry))
+
+			schedule.NextRunAt = &runAt
+			s.saveFired(schedule, firedFrom)
+			return retry
+		}
+		if err != nil && ctx.Err() != nil {
+			s.logger.Info("Shutting down, deferring scheduled run",
+				zap.String("schedule_id", schedule.ID),
+				zap.Time("run_at", runAt))
+
+			schedule.NextRunAt = &runAt
+			s.saveFired(schedule, firedFrom)
+			return 0
+		}
+		if err != nil {
+			s.logger.Error("Failed to create scheduled task", err,
+				zap.String("schedule_id", schedule.ID),
+				zap.Time("run_at", runAt))
+			continue
+		}
+
+		schedule.LastRunAt = &runAt
+		schedule.LastTaskID = task.ID
+
+		s.logger.Info("Schedule fired",
+			zap.String("schedule_id", schedule.ID),
+			zap.String("task_id", task.ID),
+			zap.Time("run_at", runAt))
+	}
+
+	next := spec.Next(now.In(loc))
+	schedule.NextRunAt = &next
+	s.saveFired(schedule, firedFrom)
+	return 0
+}
+
+// saveFired stores a schedule after firing it from its run due at firedFrom.
+// Tasks are created without holding s.mu, so a schedule deleted meanwhile is
+// left deleted, and one paused or resumed meanwhile keeps the next run set
+// through the API and only records the runs that were started.
+func (s *ScheduleService) saveFired(schedule *model.Schedule, firedFrom time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.repo.GetSchedule(schedule.ID)
+	if errors.Is(err, repository.ErrScheduleNotFound) {
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get schedule", err,
+			zap.String("schedule_id", schedule.ID))
+		return
+	}
+
+	if stored.Paused || stored.NextRunAt == nil || !stored.NextRunAt.Equal(firedFrom) {
+		schedule.Paused = stored.Paused
+		schedule.NextRunAt = stored.NextRunAt
+	}
+	s.updateSchedule(schedule)
+}
+
+func (s *ScheduleService) updateSchedule(schedule *model.Schedule) {
+	if _, err := s.repo.UpdateSchedule(schedule); err != nil {
+		s.logger.Error("Failed to update schedule", err,
+			zap.String("schedule_id", schedule.ID))
+	}
+}
+
+// dueRuns returns the runs of the schedule to start now and how many due runs
+// are dropped. A run counts as missed if it is noticed more than
+// MissedRunGrace after it fell due.
+func (s *ScheduleService) dueRuns(schedule *model.Schedule, spec cron.Schedule, loc *time.Location, now time.Time) ([]time.Time, int) {
+	var due []time.Time
+	total := 0
+	for t := schedule.NextRunAt.In(loc); !t.After(now); t = spec.Next(t) {
+		total++
+		due = append(due, t)
+		if s.config.MaxCatchUpRuns > 0 && len(due) > s.config.MaxCatchUpRuns {
+			due = due[1:]
+		}
+	}
+	if len(due) == 0 {
+		return nil, 0
+	}
+
+	latest := due[len(due)-1]
+	onTime := now.Sub(latest) <= s.config.MissedRunGrace
+
+	var runs []time.Time
+	switch schedule.MissedRunPolicy {
+	case model.MissedRunCatchUpAll:
+		runs = due
+	case model.MissedRunCatchUpOnce:
+		runs = due[len(due)-1:]
+	default:
+		if onTime {
+			runs = due[len(due)-1:]
+		}
+	}
+
+	return runs, total - len(runs)
+}
+
+// taskRequest turns a schedule's template into a task creation request
+func taskRequest(template model.TaskTemplate) dto.CreateTaskRequest {
+	return dto.CreateTaskRequest{
+		Title:       template.Title,
+		Description: template.Description,
+		Type:        template.Type,
+		Payload:     template.Payload,
+		MaxAttempts: template.MaxAttempts,
+		Timeout:     template.Timeout,
+		Priority:    template.Priority,
+	}
+}
+
+// Shutdown stops firing schedules
+func (s *ScheduleService) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down schedule service")
+	close(s.shutdownChan)
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "ctx done")
+	case <-done:
+		return nil
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// newTestScheduleService создаёт сервис расписаний поверх сервиса задач с мгновенным исполнителем
+func newTestScheduleService(t *testing.T) (*ScheduleService, repository.TaskRepositoryInterface) {
+	t.Helper()
+
+	taskRepo := repository.NewTaskRepository()
+	tasks := newInstantService(t, taskRepo, DefaultConfig())
+
+	service := NewScheduleService(repository.NewScheduleRepository(), tasks, setupTestLogger(),
+		DefaultConfig().Schedules)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+	return service, taskRepo
+}
+
+func scheduleRequest(expr string) dto.CreateScheduleRequest {
+	return dto.CreateScheduleRequest{
+		Name: "Schedule",
+		Cron: expr,
+		Task: model.TaskTemplate{Title: "Task", Description: "Description"},
+	}
+}
+
+func TestScheduleFires(t *testing.T) {
+	service, taskRepo := newTestScheduleService(t)
+
+	schedule, err := service.CreateSchedule(scheduleRequest("@every 1s"))
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", schedule.Timezone)
+	assert.Equal(t, model.MissedRunSkip, schedule.MissedRunPolicy)
+	require.NotNil(t, schedule.NextRunAt)
+
+	deadline := time.Now().Add(3 * time.Second)
+	var taskID string
+	for time.Now().Before(deadline) && taskID == "" {
+		time.Sleep(50 * time.Millisecond)
+		got, err := service.GetSchedule(schedule.ID)
+		require.NoError(t, err)
+		taskID = got.LastTaskID
+	}
+	require.NotEmpty(t, taskID, "schedule did not fire")
+
+	task := waitForStatus(t, taskRepo, taskID, model.StatusCompleted)
+	assert.Equal(t, "Task", task.Title)
+}
+
+func TestPauseResumeSchedule(t *testing.T) {
+	service, _ := newTestScheduleService(t)
+
+	schedule, err := service.CreateSchedule(scheduleRequest("0 3 * * *"))
+	require.NoError(t, err)
+
+	schedule, err = service.PauseSchedule(schedule.ID)
+	require.NoError(t, err)
+	assert.True(t, schedule.Paused)
+	assert.Nil(t, schedule.NextRunAt)
+
+	schedule, err = service.ResumeSchedule(schedule.ID)
+	require.NoError(t, err)
+	assert.False(t, schedule.Paused)
+	require.NotNil(t, schedule.NextRunAt)
+	assert.Equal(t, 3, schedule.NextRunAt.Hour())
+
+	_, err = service.PauseSchedule("42")
+	assert.ErrorIs(t, err, repository.ErrScheduleNotFound)
+}
+
+func TestMissedRuns(t *testing.T) {
+	spec, err := cron.ParseStandard("@hourly")
+	require.NoError(t, err)
+
+	first := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	// Сервис был выключен три часа: пропущены запуски в 10, 11, 12 и 13 часов
+	late := first.Add(3*time.Hour + 10*time.Minute)
+
+	tests := []struct {
+		name    string
+		policy  model.MissedRunPolicy
+		now     time.Time
+		runs    int
+		dropped int
+	}{
+		{name: "skip", policy: model.MissedRunSkip, now: late, runs: 0, dropped: 4},
+		{name: "skip on time", policy: model.MissedRunSkip, now: first.Add(10 * time.Second), runs: 1, dropped: 0},
+		{name: "catch up once", policy: model.MissedRunCatchUpOnce, now: late, runs: 1, dropped: 3},
+		{name: "catch up all", policy: model.MissedRunCatchUpAll, now: late, runs: 4, dropped: 0},
+	}
+
+	service := &ScheduleService{config: DefaultConfig().Schedules}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &model.Schedule{MissedRunPolicy: tt.policy, NextRunAt: &first}
+
+			runs, dropped := service.dueRuns(schedule, spec, time.UTC, tt.now)
+			assert.Len(t, runs, tt.runs)
+			assert.Equal(t, tt.dropped, dropped)
+		})
+	}
+
+	t.Run("catch up limit", func(t *testing.T) {
+		limited := &ScheduleService{config: ScheduleConfig{MissedRunGrace: time.Minute, MaxCatchUpRuns: 2}}
+		schedule := &model.Schedule{MissedRunPolicy: model.MissedRunCatchUpAll, NextRunAt: &first}
+
+		runs, dropped := limited.dueRuns(schedule, spec, time.UTC, late)
+		require.Len(t, runs, 2)
+		assert.Equal(t, 2, dropped)
+		// Остаются самые поздние запуски
+		assert.Equal(t, first.Add(3*time.Hour), runs[1])
+	})
+}
+
+func TestInvalidScheduleRequest(t *testing.T) {
+	service, _ := newTestScheduleService(t)
+
+	tests := map[string]struct {
+		req dto.CreateScheduleRequest
+		err error
+	}{
+		"cron":     {req: scheduleRequest("every day"), err: ErrInvalidCronExpression},
+		"timezone": {req: dto.CreateScheduleRequest{Cron: "@daily", Timezone: "Mars/Base", Task: scheduleRequest("").Task}, err: ErrInvalidTimezone},
+		"policy":   {req: dto.CreateScheduleRequest{Cron: "@daily", MissedRunPolicy: "sometimes", Task: scheduleRequest("").Task}, err: ErrInvalidMissedRunPolicy},
+		"template": {req: dto.CreateScheduleRequest{Cron: "@daily", Task: model.TaskTemplate{Title: "Task", Description: "Description", Type: "missing"}}, err: ErrInvalidTaskTemplate},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.CreateSchedule(tt.req)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+// fullQueueCreator отклоняет задачи, пока очередь заполнена
+type fullQueueCreator struct {
+	full    bool
+	created int
+}
+
+func (c *fullQueueCreator) CreateTask(_ context.Context, _ dto.CreateTaskRequest) (*model.Task, error) {
+	if c.full {
+		return nil, ErrQueueFull
+	}
+	c.created++
+	return &model.Task{ID: strconv.Itoa(c.created)}, nil
+}
+
+func (c *fullQueueCreator) ValidateTaskRequest(dto.CreateTaskRequest) error { return nil }
+func (c *fullQueueCreator) RetryAfter() time.Duration                       { return 3 * time.Second }
+
+func TestScheduleQueueFull(t *testing.T) {
+	first := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tasks := &fullQueueCreator{full: true}
+	service := &ScheduleService{
+		repo:   repository.NewScheduleRepository(),
+		tasks:  tasks,
+		logger: setupTestLogger(),
+		config: DefaultConfig().Schedules,
+	}
+
+	newSchedule := func(policy model.MissedRunPolicy) *model.Schedule {
+		schedule, err := service.repo.CreateSchedule(&model.Schedule{
+			Cron:            "@hourly",
+			Timezone:        "UTC",
+			MissedRunPolicy: policy,
+			NextRunAt:       &first,
+		})
+		require.NoError(t, err)
+		return schedule
+	}
+
+	t.Run("retried", func(t *testing.T) {
+		tasks.full = true
+		schedule := newSchedule(model.MissedRunSkip)
+
+		// Запуск, не попавший в очередь, остаётся в ожидании и повторяется позже
+		retry := service.fire(context.Background(), schedule, first.Add(time.Second))
+		assert.Equal(t, 3*time.Second, retry)
+		assert.Equal(t, first, *schedule.NextRunAt)
+		assert.Empty(t, schedule.LastTaskID)
+
+		tasks.full = false
+		assert.Zero(t, service.fire(context.Background(), schedule, first.Add(4*time.Second)))
+		assert.NotEmpty(t, schedule.LastTaskID)
+		assert.Equal(t, first.Add(time.Hour), *schedule.NextRunAt)
+	})
+
+	t.Run("missed", func(t *testing.T) {
+		tasks.full = true
+		schedule := newSchedule(model.MissedRunSkip)
+		service.fire(context.Background(), schedule, first.Add(time.Second))
+
+		// Повтор позже допустимого опоздания считается пропущенным запуском
+		tasks.full = false
+		created := tasks.created
+		assert.Zero(t, service.fire(context.Background(), schedule, first.Add(10*time.Minute)))
+		assert.Equal(t, created, tasks.created)
+		assert.Equal(t, first.Add(time.Hour), *schedule.NextRunAt)
+	})
+}
+
+// callbackCreator вызывает onCreate перед созданием каждой задачи
+type callbackCreator struct {
+	fullQueueCreator
+	onCreate func(ctx context.Context) error
+}
+
+func (c *callbackCreator) CreateTask(ctx context.Context, req dto.CreateTaskRequest) (*model.Task, error) {
+	if err := c.onCreate(ctx); err != nil {
+		return nil, err
+	}
+	return c.fullQueueCreator.CreateTask(ctx, req)
+}
+
+func TestScheduleChangedWhileFiring(t *testing.T) {
+	first := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tasks := &callbackCreator{}
+	service := &ScheduleService{
+		repo:   repository.NewScheduleRepository(),
+		tasks:  tasks,
+		logger: setupTestLogger(),
+		config: DefaultConfig().Schedules,
+	}
+
+	newSchedule := func() *model.Schedule {
+		schedule, err := service.repo.CreateSchedule(&model.Schedule{
+			Cron:            "@hourly",
+			Timezone:        "UTC",
+			MissedRunPolicy: model.MissedRunSkip,
+			NextRunAt:       &first,
+		})
+		require.NoError(t, err)
+		return schedule
+	}
+
+	t.Run("paused", func(t *testing.T) {
+		schedule := newSchedule()
+		// Задачи создаются без блокировки, поэтому расписание можно менять через API
+		tasks.onCreate = func(context.Context) error {
+			_, err := service.PauseSchedule(schedule.ID)
+			return err
+		}
+
+		assert.Zero(t, service.fire(context.Background(), schedule, first.Add(time.Second)))
+
+		stored, err := service.repo.GetSchedule(schedule.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Paused)
+		assert.Nil(t, stored.NextRunAt)
+		assert.NotEmpty(t, stored.LastTaskID)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		schedule := newSchedule()
+		tasks.onCreate = func(context.Context) error {
+			return service.DeleteSchedule(schedule.ID)
+		}
+
+		service.fire(context.Background(), schedule, first.Add(time.Second))
+
+		_, err := service.repo.GetSchedule(schedule.ID)
+		assert.ErrorIs(t, err, repository.ErrScheduleNotFound)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		schedule := newSchedule()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		tasks.onCreate = func(ctx context.Context) error {
+			return ctx.Err()
+		}
+
+		// Запуск, прерванный остановкой, остаётся в ожидании
+		assert.Zero(t, service.fire(ctx, schedule, first.Add(time.Second)))
+
+		stored, err := service.repo.GetSchedule(schedule.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first, *stored.NextRunAt)
+		assert.Empty(t, stored.LastTaskID)
+	})
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+var errSchedulerStopped = errors.New("scheduler stopped")
+
+// QueueConfig holds configuration of the task queue
+type QueueConfig struct {
+	// Capacity is the number of tasks that may wait for a worker. 0 removes the limit.
+	Capacity int
+	// AgingInterval is how long a task has to wait to gain one priority level,
+	// so low priority tasks still run under a steady stream of urgent ones.
+	// 0 disables aging.
+	AgingInterval time.Duration
+	// FullPolicy decides what happens to a new task while Capacity tasks wait
+	FullPolicy QueueFullPolicy
+	// OverflowCapacity is how many tasks the overflow policy holds back beyond
+	// Capacity until the queue has room for them
+	OverflowCapacity int
+}
+
+// queuedTask is a task waiting in the scheduler
+type queuedTask struct {
+	task *model.Task
+	// score orders the heap, lower runs first
+	score int64
+	// seq breaks ties in submission order
+	seq uint64
+	// queuedAt is when the task started to wait
+	queuedAt time.Time
+	// overflowed is set while the task waits in the overflow buffer
+	overflowed bool
+}
+
+// taskHeap implements heap.Interface over queued tasks
+type taskHeap []*queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(*queuedTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// taskScheduler is a bounded priority queue that workers pull tasks from.
+// Tasks run by priority, then in submission order within a priority level.
+//
+// With aging, a task gains one priority level for every AgingInterval it waits.
+// Because every waiting task ages at the same rate, this is the same as
+// ordering by enqueue time minus priority*AgingInterval, which does not change
+// while tasks wait and so keeps the heap valid without reordering.
+//
+// The scheduler keeps its own copy of every task it is given, so the worker
+// that pops a task owns it and may change it freely.
+type taskScheduler struct {
+	mu       sync.Mutex
+	items    taskHeap
+	seq      uint64
+	capacity int
+	aging    time.Duration
+	now      func() time.Time
+	// overflow holds admitted tasks, in order, while the heap is full
+	overflow         []*queuedTask
+	overflowCapacity int
+	// reserved counts admitted tasks that are about to be pushed
+	reserved int
+	pops     throughputMeter
+	// available and space are signalled when a task is added or removed
+	available chan struct{}
+	space     chan struct{}
+	stop      <-chan struct{}
+}
+
+func newTaskScheduler(config QueueConfig, stop <-chan struct{}) *taskScheduler {
+	overflowCapacity := 0
+	if config.FullPolicy == QueueFullOverflow {
+		overflowCapacity = max(config.OverflowCapacity, 0)
+	}
+
+	return &taskScheduler{
+		capacity:         config.Capacity,
+		aging:            config.AgingInterval,
+		now:              time.Now,
+		pops:             newThroughputMeter(),
+		overflowCapacity: overflowCapacity,
+		available:        make(chan struct{}, 1),
+		space:            make(chan struct{}, 1),
+		stop:             stop,
+	}
+}
+
+// score computes the heap key of a task entering the queue now
+func (q *taskScheduler) score(task *model.Task) int64 {
+	if q.aging <= 0 {
+		return -int64(task.Priority)
+	}
+	return q.now().UnixNano() - int64(task.Priority)*int64(q.aging)
+}
+
+// TryPush adds the task if there is room besides the places reserved for
+// admitted tasks and reports whether it did
+func (q *taskScheduler) TryPush(task *model.Task) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.capacity > 0 && len(q.items)+q.reserved >= q.capacity {
+		return false
+	}
+
+	q.seq++
+	heap.Push(&q.items, &queuedTask{task: task.Clone(), score: q.score(task), seq: q.seq, queuedAt: q.now()})
+	signal(q.available)
+	// Pass the wake-up on in case several tasks were removed while pushers waited
+	if q.capacity <= 0 || len(q.items) < q.capacity {
+		signal(q.space)
+	}
+	return true
+}
+
+// Push adds the task, waiting for room until ctx is done or the scheduler stops
+func (q *taskScheduler) Push(ctx context.Context, task *model.Task) error {
+	for {
+		if q.TryPush(task) {
+			return nil
+		}
+
+		select {
+		case <-q.space:
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "wait for queue space")
+		case <-q.stop:
+			return errSchedulerStopped
+		}
+	}
+}
+
+// Pop removes the most urgent task, waiting for one until the scheduler stops
+// or quit is closed. It returns false if it gave up waiting.
+func (q *taskScheduler) Pop(quit <-chan struct{}) (*model.Task, bool) {
+	for {
+		select {
+		case <-q.stop:
+			return nil, false
+		case <-quit:
+			return nil, false
+		default:
+		}
+
+		if task, ok := q.tryPop(); ok {
+			return task, true
+		}
+
+		select {
+		case <-q.available:
+		case <-q.stop:
+			return nil, false
+		case <-quit:
+			return nil, false
+		}
+	}
+}
+
+func (q *taskScheduler) tryPop() (*model.Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+
+	item := heap.Pop(&q.items).(*queuedTask)
+	q.pops.record(q.now())
+	q.promoteOverflow()
+	signal(q.space)
+	// Pass the wake-up on in case several tasks were added while workers slept
+	if len(q.items) > 0 {
+		signal(q.available)
+	}
+	return item.task, true
+}
+
+// Remove takes the task with the given ID out of the queue, so no worker picks
+// it up. The returned entry can be put back with Restore.
+func (q *taskScheduler) Remove(id string) (*queuedTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.task.ID == id {
+			heap.Remove(&q.items, i)
+			q.promoteOverflow()
+			signal(q.space)
+			return item, true
+		}
+	}
+
+	for i, item := range q.overflow {
+		if item.task.ID == id {
+			q.overflow = slices.Delete(q.overflow, i, i+1)
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// Restore puts an entry taken out by Remove back with the given version of
+// its task. The entry keeps its place among tasks of the same priority and
+// the priority it gained by aging. It may exceed the capacity, since it held
+// a place in the queue before. An entry taken from the overflow buffer goes
+// back to its place in the buffer while the queue is full.
+func (q *taskScheduler) Restore(item *queuedTask, task *model.Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.aging <= 0 {
+		item.score = -int64(task.Priority)
+	} else {
+		item.score += int64(item.task.Priority-task.Priority) * int64(q.aging)
+	}
+	item.task = task.Clone()
+
+	if item.overflowed && q.capacity > 0 && len(q.items) >= q.capacity {
+		i, _ := slices.BinarySearchFunc(q.overflow, item.seq, func(other *queuedTask, seq uint64) int {
+			return cmp.Compare(other.seq, seq)
+		})
+		q.overflow = slices.Insert(q.overflow, i, item)
+		return
+	}
+
+	item.overflowed = false
+	heap.Push(&q.items, item)
+	signal(q.available)
+}
+
+// Len returns the number of waiting tasks, including those in the overflow buffer
+func (q *taskScheduler) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items) + len(q.overflow)
+}
+
+// OldestWait returns how long the task that has waited longest has been
+// waiting, or 0 if no task waits
+func (q *taskScheduler) OldestWait() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var oldest time.Time
+	for _, items := range [][]*queuedTask{q.items, q.overflow} {
+		for _, item := range items {
+			if oldest.IsZero() || item.queuedAt.Before(oldest) {
+				oldest = item.queuedAt
+			}
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return q.now().Sub(oldest)
+}
+
+// signal does a non-blocking send on a wake-up channel
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
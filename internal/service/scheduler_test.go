@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+func newQueuedTask(id string, priority int) *model.Task {
+	return &model.Task{ID: id, Priority: priority}
+}
+
+func popIDs(t *testing.T, q *taskScheduler, n int) []string {
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		task, ok := q.Pop(nil)
+		require.True(t, ok)
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	q := newTaskScheduler(QueueConfig{Capacity: 10}, make(chan struct{}))
+
+	require.True(t, q.TryPush(newQueuedTask("low-1", 1)))
+	require.True(t, q.TryPush(newQueuedTask("high-1", 9)))
+	require.True(t, q.TryPush(newQueuedTask("low-2", 1)))
+	require.True(t, q.TryPush(newQueuedTask("high-2", 9)))
+	require.True(t, q.TryPush(newQueuedTask("normal", 5)))
+
+	// Сначала по приоритету, внутри одного уровня — в порядке поступления
+	assert.Equal(t, []string{"high-1", "high-2", "normal", "low-1", "low-2"}, popIDs(t, q, 5))
+}
+
+func TestSchedulerAging(t *testing.T) {
+	now := time.Now()
+	q := newTaskScheduler(QueueConfig{Capacity: 10, AgingInterval: time.Second}, make(chan struct{}))
+	q.now = func() time.Time { return now }
+
+	require.True(t, q.TryPush(newQueuedTask("old-low", 1)))
+
+	// Через 5 секунд старая задача поднялась на 5 уровней: она обгоняет
+	// новую задачу с приоритетом 5, но не с приоритетом 9
+	now = now.Add(5 * time.Second)
+	require.True(t, q.TryPush(newQueuedTask("new-normal", 5)))
+	require.True(t, q.TryPush(newQueuedTask("new-high", 9)))
+
+	assert.Equal(t, []string{"new-high", "old-low", "new-normal"}, popIDs(t, q, 3))
+}
+
+func TestSchedulerCapacity(t *testing.T) {
+	q := newTaskScheduler(QueueConfig{Capacity: 1}, make(chan struct{}))
+
+	require.True(t, q.TryPush(newQueuedTask("1", 5)))
+	assert.False(t, q.TryPush(newQueuedTask("2", 5)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Push(ctx, newQueuedTask("2", 5)), context.DeadlineExceeded)
+
+	// Освободившееся место будит ожидающего отправителя
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(context.Background(), newQueuedTask("3", 5)) }()
+
+	assert.Equal(t, []string{"1"}, popIDs(t, q, 1))
+	require.NoError(t, <-pushed)
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestSchedulerStop(t *testing.T) {
+	stop := make(chan struct{})
+	q := newTaskScheduler(QueueConfig{Capacity: 10}, stop)
+
+	popped := make(chan bool)
+	go func() {
+		_, ok := q.Pop(nil)
+		popped <- ok
+	}()
+
+	// Закрытый quit прерывает ожидание только своего воркера
+	quit := make(chan struct{})
+	close(quit)
+	_, ok := q.Pop(quit)
+	assert.False(t, ok)
+
+	close(stop)
+	assert.False(t, <-popped)
+}
+
+func TestSchedulerRestore(t *testing.T) {
+	q := newTaskScheduler(QueueConfig{Capacity: 10}, make(chan struct{}))
+
+	require.True(t, q.TryPush(newQueuedTask("1", 5)))
+	require.True(t, q.TryPush(newQueuedTask("2", 5)))
+	require.True(t, q.TryPush(newQueuedTask("3", 5)))
+
+	_, ok := q.Remove("missing")
+	assert.False(t, ok)
+
+	// Возвращённая задача сохраняет своё место в очереди
+	item, ok := q.Remove("1")
+	require.True(t, ok)
+	assert.Equal(t, 2, q.Len())
+	q.Restore(item, newQueuedTask("1", 5))
+
+	// Изменённый приоритет учитывается
+	item, ok = q.Remove("3")
+	require.True(t, ok)
+	q.Restore(item, newQueuedTask("3", 9))
+
+	assert.Equal(t, []string{"3", "1", "2"}, popIDs(t, q, 3))
+}
+
+func TestSchedulerOldestWait(t *testing.T) {
+	now := time.Now()
+	q := newTaskScheduler(QueueConfig{Capacity: 10}, make(chan struct{}))
+	q.now = func() time.Time { return now }
+
+	assert.Zero(t, q.OldestWait())
+
+	require.True(t, q.TryPush(newQueuedTask("low", 1)))
+	now = now.Add(3 * time.Second)
+	require.True(t, q.TryPush(newQueuedTask("high", 9)))
+	now = now.Add(time.Second)
+
+	// Учитывается самая давно ждущая задача, а не первая в очереди
+	assert.Equal(t, 4*time.Second, q.OldestWait())
+	assert.Equal(t, []string{"high"}, popIDs(t, q, 1))
+	assert.Equal(t, 4*time.Second, q.OldestWait())
+	assert.Equal(t, []string{"low"}, popIDs(t, q, 1))
+	assert.Zero(t, q.OldestWait())
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// Result counts of task searches
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+var (
+	ErrInvalidSearchQuery = errors.New("invalid search query")
+	ErrSearchUnavailable  = errors.New("task search is not available")
+)
+
+// SearchTasks returns the tasks whose title, description, result or error
+// match the query, best match first. A limit of 0 returns DefaultSearchLimit tasks.
+func (s *TaskService) SearchTasks(query string, limit int) ([]repository.SearchResult, error) {
+	searcher, ok := s.repo.(repository.TaskSearcher)
+	if !ok {
+		return nil, ErrSearchUnavailable
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.Wrap(ErrInvalidSearchQuery, "q is required")
+	}
+
+	switch {
+	case limit < 0 || limit > MaxSearchLimit:
+		return nil, errors.Wrapf(ErrInvalidSearchQuery, "limit must be between 1 and %d", MaxSearchLimit)
+	case limit == 0:
+		limit = DefaultSearchLimit
+	}
+
+	results, err := searcher.SearchTasks(query, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "search tasks")
+	}
+
+	for i := range results {
+		results[i].Task = s.withProgress(results[i].Task)
+	}
+	return results, nil
+}
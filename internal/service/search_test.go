@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestSearchTasks(t *testing.T) {
+	repo, err := repository.NewIndexedTaskRepository(repository.NewTaskRepository())
+	require.NoError(t, err)
+
+	service := NewTaskService(repo, setupTestLogger(), DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	for _, title := range []string{"Invoice export", "Weekly report", "Invoice cleanup"} {
+		task := model.NewTask(title, "", DefaultTaskType, nil)
+		task.UpdateStatus(model.StatusCompleted)
+		_, err := repo.CreateTask(task)
+		require.NoError(t, err)
+	}
+
+	results, err := service.SearchTasks("invoice exp", 0)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Invoice export", results[0].Task.Title)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results, err = service.SearchTasks("invoice", 1)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// Некорректные запросы
+	_, err = service.SearchTasks(" ", 0)
+	assert.ErrorIs(t, err, ErrInvalidSearchQuery)
+	_, err = service.SearchTasks("invoice", MaxSearchLimit+1)
+	assert.ErrorIs(t, err, ErrInvalidSearchQuery)
+
+	// Репозиторий без индекса не поддерживает поиск
+	plain := NewTaskService(repository.NewTaskRepository(), setupTestLogger(), DefaultConfig())
+	defer plain.Shutdown(context.Background())
+	_, err = plain.SearchTasks("invoice", 0)
+	assert.ErrorIs(t, err, ErrSearchUnavailable)
+}
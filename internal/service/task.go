@@ -1,9 +1,13 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,42 +20,97 @@ import (
 	"github.com/nessibeliyeltay/task-api/pkg/logger"
 )
 
-var ErrInvalidTaskID = errors.New("invalid task ID format")
+var (
+	ErrInvalidTaskID   = errors.New("invalid task ID format")
+	ErrUnknownTaskType = errors.New("unknown task type")
+	ErrInvalidPayload  = errors.New("invalid task payload")
+	ErrResultTooLarge  = errors.New("task result too large")
+	ErrInvalidPriority = errors.New("invalid task priority")
+)
 
 type TaskServiceInterface interface {
 	CreateTask(ctx context.Context, req dto.CreateTaskRequest) (*model.Task, error)
-	ListTasks() ([]*model.Task, error)
+	CreateTaskBatch(ctx context.Context, mode BatchMode, reqs []dto.CreateTaskRequest) (*BatchResult, error)
+	ListTasks(req dto.ListTasksRequest) (*TaskPage, error)
+	SearchTasks(query string, limit int) ([]repository.SearchResult, error)
 	GetTask(id string) (*model.Task, error)
+	EditTask(id string, req dto.EditTaskRequest) (*model.Task, error)
 	DeleteTask(id string) error
+	CancelTask(ctx context.Context, id, reason string) (*model.Task, error)
+	ListDeadLetterTasks() ([]*model.Task, error)
+	RedriveTask(ctx context.Context, id string) (*model.Task, error)
+	CreateWorkflow(ctx context.Context, req dto.CreateWorkflowRequest) (*model.Workflow, error)
+	GetWorkflow(id string) (*model.Workflow, error)
+	RetryAfter() time.Duration
+	SubscribeEvents(filter EventFilter, lastID uint64) *Subscription
+	WaitTask(ctx context.Context, id string, timeout time.Duration) (*model.Task, error)
 	Shutdown(ctx context.Context) error
 }
 
 type TaskService struct {
 	repo            repository.TaskRepositoryInterface
 	logger          *logger.Logger
+	config          Config
 	processingDelay time.Duration
-	workerCount     int
-	taskQueue       chan *model.Task
-	wg              sync.WaitGroup
-	ctx             context.Context
-	cancel          context.CancelFunc
-	shutdownChan    chan struct{}
+	workers         *workerPool
+	queue           *taskScheduler
+	delayed         *delayQueue
+	dependencies    *dependencyTracker
+	events          *EventBus
+	executors       *ExecutorRegistry
+	running         map[string]*runningTask
+	cancelled       map[string]struct{}
+	runningMu       sync.Mutex
+	// idempotencyMu serializes the creation of tasks with an idempotency key
+	idempotencyMu sync.Mutex
+	// abandoned counts executors still running after their worker gave up on them
+	abandoned    atomic.Int32
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	shutdownChan chan struct{}
 }
 
-func NewTaskService(repo repository.TaskRepositoryInterface, logger *logger.Logger) *TaskService {
+func NewTaskService(repo repository.TaskRepositoryInterface, logger *logger.Logger, config Config) *TaskService {
 	ctx, cancel := context.WithCancel(context.Background())
 	service := &TaskService{
 		repo:            repo,
 		logger:          logger,
+		config:          config,
 		processingDelay: 2 * time.Minute, // Default processing time
-		workerCount:     5,               // Default number of workers
-		taskQueue:       make(chan *model.Task, 100),
+		workers:         newWorkerPool(),
+		executors:       NewExecutorRegistry(),
+		running:         make(map[string]*runningTask),
+		cancelled:       make(map[string]struct{}),
 		ctx:             ctx,
 		cancel:          cancel,
 		shutdownChan:    make(chan struct{}),
 	}
 
-	service.startWorkers(ctx)
+	service.queue = newTaskScheduler(config.Queue, service.shutdownChan)
+	service.delayed = newDelayQueue()
+	service.dependencies = newDependencyTracker()
+	service.events = NewEventBus(config.Events)
+
+	service.RegisterExecutor(DefaultTaskType, ExecutorFunc(service.simulateProcessing))
+	for taskType, executor := range config.Executors {
+		service.RegisterExecutor(taskType, executor)
+	}
+
+	service.recoverTasks()
+
+	if err := service.ResizeWorkers(DefaultWorkerCount); err != nil {
+		logger.Error("Failed to start workers", err)
+	}
+
+	service.wg.Add(2)
+	go service.runDelayQueue()
+	go service.runDependencyResolver()
+
+	if config.Recovery.Enabled && config.Recovery.ReapInterval > 0 {
+		service.wg.Add(1)
+		go service.reapStaleTasks()
+	}
 
 	return service
 }
@@ -61,93 +120,195 @@ func (s *TaskService) SetProcessingDelay(delay time.Duration) {
 	s.processingDelay = delay
 }
 
-// SetWorkerCount sets the number of concurrent workers
-func (s *TaskService) SetWorkerCount(count int) {
-	if count < 1 {
-		count = 1
-	}
-	s.workerCount = count
-}
-
-// startWorkers starts the worker pool
-func (s *TaskService) startWorkers(ctx context.Context) {
-	for i := 0; i < s.workerCount; i++ {
-		workerID := i
-		s.wg.Add(1)
-		go func() {
-			defer s.wg.Done()
-			s.logger.Info("Worker started", zap.Int("worker_id", workerID))
-
-			for {
-				select {
-				case <-ctx.Done():
-					s.logger.Info("Worker stopping due to context cancellation", zap.Int("worker_id", workerID))
-					return
-				case <-s.shutdownChan:
-					s.logger.Info("Worker stopping due to shutdown signal", zap.Int("worker_id", workerID))
-					return
-				default:
-					select {
-					case <-ctx.Done():
-						s.logger.Info("Worker stopping due to context cancellation", zap.Int("worker_id", workerID))
-						return
-					case <-s.shutdownChan:
-						s.logger.Info("Worker stopping due to shutdown signal", zap.Int("worker_id", workerID))
-						return
-					case task := <-s.taskQueue:
-						if task == nil {
-							continue
-						}
-
-						task.UpdateStatus(model.StatusProcessing)
-						if _, err := s.repo.UpdateTask(task); err != nil {
-							s.logger.Error("Failed to update task status", err,
-								zap.String("task_id", task.ID))
-							continue
-						}
-
-						s.logger.Info("Task processing started",
-							zap.String("task_id", task.ID),
-							zap.Time("started_at", *task.StartedAt),
-							zap.String("duration", task.DurationStr))
-
-						// Simulate processing with context
-						select {
-						case <-ctx.Done():
-							s.logger.Info("Task processing cancelled", zap.String("task_id", task.ID))
-							return
-						case <-s.shutdownChan:
-							s.logger.Info("Task processing cancelled due to shutdown", zap.String("task_id", task.ID))
-							return
-						case <-time.After(s.processingDelay):
-							// Continue processing
-						}
-
-						// Complete task
-						task.UpdateStatus(model.StatusCompleted)
-						task.Result = "Task completed successfully"
-						if _, err := s.repo.UpdateTask(task); err != nil {
-							s.logger.Error("Failed to update task status", err,
-								zap.String("task_id", task.ID))
-							continue
-						}
-
-						s.logger.Info("Task completed",
-							zap.String("task_id", task.ID),
-							zap.String("result", task.Result),
-							zap.String("duration", task.DurationStr))
-					}
-				}
-			}
-		}()
+// processTask runs the task through the executor registered for its type and
+// records the outcome. It returns false if processing was interrupted by shutdown.
+func (s *TaskService) processTask(ctx context.Context, task *model.Task) bool {
+	taskCtx, ok := s.startRunning(ctx, task)
+	if !ok {
+		s.logger.Info("Skipping cancelled task", zap.String("task_id", task.ID))
+		return true
+	}
+	defer s.finishRunning(task.ID)
+
+	task.UpdateStatus(model.StatusProcessing)
+	task.Attempts++
+	task.NextRetryAt = nil
+	task.Progress = nil
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return true
+	}
+
+	s.logger.Info("Task processing started",
+		zap.String("task_id", task.ID),
+		zap.Time("started_at", *task.StartedAt),
+		zap.String("duration", task.DurationStr))
+
+	executor, ok := s.executors.Get(task.Type)
+	if !ok {
+		s.failTask(task, errors.Wrap(ErrExecutorNotFound, task.Type))
+		return true
+	}
+
+	if ctx.Err() != nil {
+		s.logger.Info("Task processing cancelled due to shutdown", zap.String("task_id", task.ID))
+		return false
+	}
+	if reason, cancelled := s.cancellation(task.ID); cancelled {
+		s.recordCancelled(task, reason)
+		return true
+	}
+
+	result, err := s.execute(taskCtx, executor, task)
+	if err != nil && ctx.Err() != nil {
+		s.logger.Info("Task processing cancelled due to shutdown", zap.String("task_id", task.ID))
+		return false
+	}
+	s.recordProgress(task)
+	if reason, cancelled := s.cancellation(task.ID); cancelled {
+		s.recordCancelled(task, reason)
+		return true
+	}
+	if err != nil {
+		s.handleFailure(task, err)
+		return true
+	}
+
+	result, err = s.normalizeResult(result)
+	if err != nil {
+		s.failTask(task, err)
+		return true
+	}
+
+	task.UpdateStatus(model.StatusCompleted)
+	task.Result = result
+	if task.Progress != nil {
+		task.Progress.Percent = 100
+	}
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return true
+	}
+
+	s.logger.Info("Task completed",
+		zap.String("task_id", task.ID),
+		zap.Int("result_size", len(task.Result)),
+		zap.String("duration", task.DurationStr))
+
+	return true
+}
+
+// updateTask stores the task, publishes the change and lets tasks that depend
+// on it react once it has finished
+func (s *TaskService) updateTask(task *model.Task) error {
+	if _, err := s.repo.UpdateTask(task); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	s.publish(task)
+
+	if task.Status.IsTerminal() {
+		s.dependencies.finished(task.ID)
+	}
+	return nil
+}
+
+// failTask moves the task to the failed status and records the error
+func (s *TaskService) failTask(task *model.Task, taskErr error) {
+	task.UpdateStatus(model.StatusFailed)
+	task.Error = taskErr.Error()
+	task.FailureReason = failureReason(taskErr)
+	if err := s.updateTask(task); err != nil {
+		s.logger.Error("Failed to update task status", err,
+			zap.String("task_id", task.ID))
+		return
 	}
+
+	s.logger.Info("Task failed",
+		zap.String("task_id", task.ID),
+		zap.String("error", task.Error),
+		zap.String("duration", task.DurationStr))
+}
+
+// simulateProcessing is the executor for DefaultTaskType. It waits for the
+// configured processing delay, reporting progress in ten steps, and reports success.
+func (s *TaskService) simulateProcessing(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+	const steps = 10
+
+	if s.processingDelay <= 0 {
+		return TextResult("Task completed successfully"), nil
+	}
+
+	// A delay shorter than the steps would give the ticker no interval
+	interval := s.processingDelay / steps
+	if interval <= 0 {
+		interval = s.processingDelay
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for step := 1; step <= steps; step++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			ReportProgress(ctx, float64(step*100/steps), "processing",
+				fmt.Sprintf("step %d of %d", step, steps))
+		}
+	}
+	return TextResult("Task completed successfully"), nil
+}
+
+// normalizeResult makes sure the executor output is valid JSON within the
+// configured size limit. Output that is not JSON is kept as a JSON string so
+// plain text results stay readable.
+func (s *TaskService) normalizeResult(result json.RawMessage) (json.RawMessage, error) {
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	if !json.Valid(result) {
+		result = TextResult(string(result))
+	}
+
+	if s.config.MaxResultSize > 0 && len(result) > s.config.MaxResultSize {
+		return nil, errors.Wrapf(ErrResultTooLarge, "%d bytes exceeds limit of %d bytes",
+			len(result), s.config.MaxResultSize)
+	}
+
+	return result, nil
+}
+
+// RegisterExecutor sets the executor used for tasks of the given type
+func (s *TaskService) RegisterExecutor(taskType string, executor TaskExecutor) {
+	s.executors.Register(taskType, executor)
 }
 
 func (s *TaskService) CreateTask(ctx context.Context, req dto.CreateTaskRequest) (*model.Task, error) {
-	task := model.NewTask(req.Title, req.Description)
+	if req.IdempotencyKey != "" && s.config.IdempotencyWindow > 0 {
+		return s.createIdempotent(req)
+	}
+	return s.createTask(req)
+}
+
+// createTask stores a new task and hands it to the queues
+func (s *TaskService) createTask(req dto.CreateTaskRequest) (*model.Task, error) {
+	task, err := s.newTask(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := readyTasks([]*model.Task{task})
+	if err := s.admit(ready); err != nil {
+		return nil, err
+	}
 
-	task, err := s.repo.CreateTask(task)
+	task, err = s.repo.CreateTask(task)
 	if err != nil {
+		s.queue.Unreserve(ready)
 		return nil, errors.Wrap(err, "create task")
 	}
 
@@ -155,23 +316,149 @@ func (s *TaskService) CreateTask(ctx context.Context, req dto.CreateTaskRequest)
 		zap.String("task_id", task.ID),
 		zap.String("status", string(task.Status)))
 
-	select {
-	case s.taskQueue <- task:
+	s.dispatch(task)
+	return task, nil
+}
+
+// dispatch hands a newly created task to the part of the service that runs it
+// next, depending on whether it is blocked, scheduled or ready to run. A task
+// that is ready to run must have been admitted to the queue.
+func (s *TaskService) dispatch(task *model.Task) {
+	s.publish(task)
+
+	switch task.Status {
+	case model.StatusBlocked:
+		s.dependencies.watch(task)
+		s.logger.Info("Task blocked on dependencies",
+			zap.String("task_id", task.ID),
+			zap.Strings("depends_on", task.DependsOn))
+		return
+	case model.StatusScheduled:
+		s.delayed.Add(task.ID, *task.RunAt)
+		s.logger.Info("Task scheduled",
+			zap.String("task_id", task.ID),
+			zap.Time("run_at", *task.RunAt))
+		return
+	}
+
+	if s.queue.PushReserved(task) {
 		s.logger.Info("Task queued for processing",
+			zap.String("task_id", task.ID),
+			zap.Int("priority", task.Priority))
+	} else {
+		s.logger.Warn("Task queue is full, task waits in the overflow buffer",
 			zap.String("task_id", task.ID))
-	case <-ctx.Done():
-		return nil, errors.Wrap(ctx.Err(), "task queue is full")
-	default:
-		s.logger.Warn("Task queue is full, task will be processed when space is available",
-			zap.String("task_id", task.ID))
-		s.taskQueue <- task
+	}
+}
+
+// ValidateTaskRequest checks a task request without creating the task
+func (s *TaskService) ValidateTaskRequest(req dto.CreateTaskRequest) error {
+	_, err := s.newTask(req)
+	return err
+}
+
+// newTask validates the request and builds the task it describes
+func (s *TaskService) newTask(req dto.CreateTaskRequest) (*model.Task, error) {
+	taskType := req.Type
+	if taskType == "" {
+		taskType = DefaultTaskType
+	}
+
+	payload := req.Payload
+	if bytes.Equal(bytes.TrimSpace(payload), []byte("null")) {
+		payload = nil
+	}
+
+	if err := s.validateTaskType(taskType, payload); err != nil {
+		return nil, err
+	}
+
+	maxAttempts, backoff, err := s.retryPolicy(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := s.taskTimeout(req)
+	if err != nil {
+		return nil, err
+	}
+
+	priority, err := taskPriority(req)
+	if err != nil {
+		return nil, err
+	}
+
+	runAt, err := taskRunAt(req)
+	if err != nil {
+		return nil, err
+	}
+
+	dependsOn, err := s.taskDependencies(req.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CallbackURL != "" {
+		if err := checkWebhookURL(req.CallbackURL, ErrInvalidCallbackURL, s.config.Webhooks.AllowPrivateTargets); err != nil {
+			return nil, err
+		}
+	}
+
+	task := model.NewTask(req.Title, req.Description, taskType, payload)
+	task.MaxAttempts = maxAttempts
+	task.Backoff = backoff
+	task.Timeout = timeout
+	task.Priority = priority
+	task.RunAt = runAt
+	task.DependsOn = dependsOn
+	task.CallbackURL = req.CallbackURL
+	if req.IdempotencyKey != "" {
+		task.IdempotencyKey = req.IdempotencyKey
+		task.RequestHash = requestHash(req)
+	}
+	switch {
+	case len(dependsOn) > 0:
+		// A blocked task keeps its run_at and is scheduled once it is unblocked
+		task.Status = model.StatusBlocked
+	case runAt != nil:
+		task.Status = model.StatusScheduled
 	}
 
 	return task, nil
 }
 
-func (s *TaskService) ListTasks() ([]*model.Task, error) {
-	return s.repo.ListTasks() //nolint:wrapcheck
+// taskPriority resolves the priority of a new task from the request
+func taskPriority(req dto.CreateTaskRequest) (int, error) {
+	if req.Priority == nil {
+		return model.DefaultPriority, nil
+	}
+
+	priority := *req.Priority
+	if priority < model.MinPriority || priority > model.MaxPriority {
+		return 0, errors.Wrapf(ErrInvalidPriority, "priority must be between %d and %d",
+			model.MinPriority, model.MaxPriority)
+	}
+	return priority, nil
+}
+
+// validateTaskType checks that an executor is registered for the task type and
+// that it accepts the payload
+func (s *TaskService) validateTaskType(taskType string, payload json.RawMessage) error {
+	executor, ok := s.executors.Get(taskType)
+	if !ok {
+		return errors.Wrapf(ErrUnknownTaskType, "%q", taskType)
+	}
+
+	validator, ok := executor.(PayloadValidator)
+	if !ok {
+		return nil
+	}
+
+	if err := validator.ValidatePayload(payload); err != nil {
+		return errors.Wrap(ErrInvalidPayload, err.Error())
+	}
+
+	return nil
 }
 
 func (s *TaskService) GetTask(id string) (*model.Task, error) {
@@ -188,7 +475,7 @@ func (s *TaskService) GetTask(id string) (*model.Task, error) {
 		zap.String("task_id", task.ID),
 		zap.String("status", string(task.Status)))
 
-	return task, nil
+	return s.withProgress(task), nil
 }
 
 func (s *TaskService) DeleteTask(id string) error {
@@ -201,6 +488,9 @@ func (s *TaskService) DeleteTask(id string) error {
 		return errors.Wrap(err, "delete task")
 	}
 
+	// Tasks waiting for the deleted task can no longer run
+	s.dependencies.finished(id)
+
 	s.logger.Info("Task deleted",
 		zap.String("task_id", id))
 
@@ -211,6 +501,8 @@ func (s *TaskService) DeleteTask(id string) error {
 func (s *TaskService) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down task service")
 	close(s.shutdownChan)
+	s.cancel()
+	s.events.Close()
 
 	done := make(chan struct{})
 	go func() {
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -22,13 +24,20 @@ func setupTestLogger() *logger.Logger {
 	return logger.New(config)
 }
 
+// newMockRepository создаёт мок репозитория, в котором на момент запуска сервиса нет задач
+func newMockRepository(ctrl *gomock.Controller) *mocks.MockTaskRepositoryInterface {
+	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo.EXPECT().ListTasks().Return(nil, nil)
+	return mockRepo
+}
+
 func TestCreateTask(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	defer service.Shutdown(context.Background())
 
 	service.SetProcessingDelay(100 * time.Millisecond)
@@ -72,9 +81,9 @@ func TestGetTask(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(100 * time.Millisecond)
 	service.SetWorkerCount(5)
 
@@ -118,9 +127,9 @@ func TestListTasks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(100 * time.Millisecond)
 	service.SetWorkerCount(5)
 
@@ -142,23 +151,24 @@ func TestListTasks(t *testing.T) {
 		}
 
 		mockRepo.EXPECT().
-			ListTasks().
+			QueryTasks(repository.TaskQuery{Sort: repository.SortByCreatedAt, Limit: DefaultListLimit + 1}).
 			Return(expectedTasks, nil)
 
-		tasks, err := service.ListTasks()
+		page, err := service.ListTasks(dto.ListTasksRequest{})
 		assert.NoError(t, err)
-		assert.Equal(t, expectedTasks, tasks)
+		assert.Equal(t, expectedTasks, page.Tasks)
+		assert.Empty(t, page.NextCursor)
 	})
 
 	// Тест пустого списка
 	t.Run("empty list", func(t *testing.T) {
 		mockRepo.EXPECT().
-			ListTasks().
+			QueryTasks(gomock.Any()).
 			Return([]*model.Task{}, nil)
 
-		tasks, err := service.ListTasks()
+		page, err := service.ListTasks(dto.ListTasksRequest{})
 		assert.NoError(t, err)
-		assert.Empty(t, tasks)
+		assert.Empty(t, page.Tasks)
 	})
 
 	// Даем время на завершение всех горутин
@@ -169,9 +179,9 @@ func TestDeleteTask(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(100 * time.Millisecond)
 	service.SetWorkerCount(5)
 
@@ -206,9 +216,9 @@ func TestWorkerPool(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(100 * time.Millisecond)
 	service.SetWorkerCount(2)
 
@@ -279,9 +289,9 @@ func TestGracefulShutdown(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(100 * time.Millisecond)
 	service.SetWorkerCount(2)
 
@@ -329,9 +339,9 @@ func TestShutdownTimeout(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockRepo := mocks.NewMockTaskRepositoryInterface(ctrl)
+	mockRepo := newMockRepository(ctrl)
 	mockLogger := setupTestLogger()
-	service := NewTaskService(mockRepo, mockLogger)
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
 	service.SetProcessingDelay(0) // убираем искусственную задержку
 	service.SetWorkerCount(1)
 
@@ -386,3 +396,132 @@ func TestShutdownTimeout(t *testing.T) {
 	// Даём немного времени воркеру завершиться (необязательно, если Shutdown ждёт)
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestExecutorFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := newMockRepository(ctrl)
+	mockLogger := setupTestLogger()
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	service.RegisterExecutor(DefaultTaskType, ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+		return nil, errors.New("connection refused")
+	}))
+
+	ctx := context.Background()
+	req := dto.CreateTaskRequest{
+		Title:       "Test Task",
+		Description: "Test Description",
+	}
+
+	failedCh := make(chan struct{})
+
+	mockRepo.EXPECT().
+		CreateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			return task, nil
+		})
+
+	// Ожидаем обновление статуса на processing
+	mockRepo.EXPECT().
+		UpdateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			assert.Equal(t, model.StatusProcessing, task.Status)
+			return task, nil
+		})
+
+	// Ожидаем обновление статуса на failed с текстом ошибки
+	mockRepo.EXPECT().
+		UpdateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			assert.Equal(t, model.StatusFailed, task.Status)
+			assert.Equal(t, "connection refused", task.Error)
+			assert.NotNil(t, task.CompletedAt)
+			close(failedCh)
+			return task, nil
+		})
+
+	_, err := service.CreateTask(ctx, req)
+	assert.NoError(t, err)
+
+	select {
+	case <-failedCh:
+	case <-time.After(time.Second):
+		t.Fatal("task was not marked as failed")
+	}
+}
+
+func TestCreateTaskUnknownType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := newMockRepository(ctrl)
+	mockLogger := setupTestLogger()
+	service := NewTaskService(mockRepo, mockLogger, DefaultConfig())
+	defer service.Shutdown(context.Background())
+
+	req := dto.CreateTaskRequest{
+		Title:       "Test Task",
+		Description: "Test Description",
+		Type:        "unknown",
+	}
+
+	// Задача с незарегистрированным типом не должна попасть в репозиторий
+	task, err := service.CreateTask(context.Background(), req)
+	assert.Nil(t, task)
+	assert.ErrorIs(t, err, ErrUnknownTaskType)
+}
+
+func TestResultTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := newMockRepository(ctrl)
+	mockLogger := setupTestLogger()
+	config := DefaultConfig()
+	config.MaxResultSize = 8
+	service := NewTaskService(mockRepo, mockLogger, config)
+	defer service.Shutdown(context.Background())
+
+	service.RegisterExecutor(DefaultTaskType, ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+		return json.RawMessage(`{"rows": [1, 2, 3]}`), nil
+	}))
+
+	failedCh := make(chan struct{})
+
+	mockRepo.EXPECT().
+		CreateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			return task, nil
+		})
+
+	// processing, затем failed из-за превышения лимита размера результата
+	mockRepo.EXPECT().
+		UpdateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			return task, nil
+		})
+	mockRepo.EXPECT().
+		UpdateTask(gomock.Any()).
+		DoAndReturn(func(task *model.Task) (*model.Task, error) {
+			assert.Equal(t, model.StatusFailed, task.Status)
+			assert.Contains(t, task.Error, ErrResultTooLarge.Error())
+			assert.Empty(t, task.Result)
+			close(failedCh)
+			return task, nil
+		})
+
+	_, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Test Task",
+		Description: "Test Description",
+	})
+	assert.NoError(t, err)
+
+	select {
+	case <-failedCh:
+	case <-time.After(time.Second):
+		t.Fatal("task was not marked as failed")
+	}
+}
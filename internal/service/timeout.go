@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// maxAbandonedExecutors is the number of executors that may keep running after
+// their deadline. Past it, workers wait for a timed-out executor to return.
+const maxAbandonedExecutors = 100
+
+var (
+	ErrInvalidTimeout = errors.New("invalid task timeout")
+	ErrTaskTimedOut   = errors.New("task timed out")
+)
+
+// TimeoutConfig holds the server-wide execution timeout limits
+type TimeoutConfig struct {
+	// Default is used when a task does not set a timeout, 0 means no timeout
+	Default time.Duration
+	// Max is the longest timeout a task may ask for, 0 means no limit
+	Max time.Duration
+}
+
+// taskTimeout resolves the execution timeout of a new task from the request
+// and the server limits
+func (s *TaskService) taskTimeout(req dto.CreateTaskRequest) (time.Duration, error) {
+	limits := s.config.Timeout
+
+	if req.Timeout == "" {
+		return limits.Default, nil
+	}
+
+	timeout, err := time.ParseDuration(req.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, errors.Wrapf(ErrInvalidTimeout, "%q is not a positive duration", req.Timeout)
+	}
+
+	if limits.Max > 0 && timeout > limits.Max {
+		return 0, errors.Wrapf(ErrInvalidTimeout, "timeout must not exceed %s", limits.Max)
+	}
+
+	return timeout, nil
+}
+
+// executorResult is what an executor returned
+type executorResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// execute runs the executor under the task deadline. The worker stops waiting
+// as soon as the context is done, so an executor that ignores its context can
+// not pin the worker; it is left to finish in the background with its own copy
+// of the task, so it can not touch the task the worker retries or stores.
+// Shutdown still waits for it. Once maxAbandonedExecutors are left behind,
+// workers wait for timed-out executors to return instead.
+func (s *TaskService) execute(ctx context.Context, executor TaskExecutor, task *model.Task) (json.RawMessage, error) {
+	execCtx := ctx
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+
+	// settled is set by whichever comes first: the executor returning or the
+	// worker abandoning it. The executor releases its place if it lost.
+	var settled atomic.Bool
+	done := make(chan executorResult, 1)
+	own := task.Clone()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		result, err := executor.Execute(execCtx, own)
+		if !settled.CompareAndSwap(false, true) {
+			s.abandoned.Add(-1)
+			s.logger.Info("Abandoned executor returned", zap.String("task_id", task.ID))
+		}
+		done <- executorResult{result: result, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(execCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, errors.Wrapf(ErrTaskTimedOut, "after %s", task.Timeout)
+		}
+		return res.result, res.err
+	case <-execCtx.Done():
+	}
+
+	if !s.abandon(task, &settled) {
+		<-done
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, errors.Wrapf(ErrTaskTimedOut, "after %s", task.Timeout)
+}
+
+// abandon leaves a timed-out executor running in the background unless there
+// are too many of them already or it has just returned
+func (s *TaskService) abandon(task *model.Task, settled *atomic.Bool) bool {
+	abandoned := s.abandoned.Add(1)
+	if abandoned > maxAbandonedExecutors || !settled.CompareAndSwap(false, true) {
+		s.abandoned.Add(-1)
+		if abandoned > maxAbandonedExecutors {
+			s.logger.Warn("Too many abandoned executors, waiting for the executor to return",
+				zap.String("task_id", task.ID),
+				zap.Int32("abandoned", abandoned-1))
+		}
+		return false
+	}
+
+	s.logger.Warn("Executor did not stop at its deadline, leaving it to finish in the background",
+		zap.String("task_id", task.ID),
+		zap.Int32("abandoned", abandoned))
+	return true
+}
+
+// AbandonedExecutors returns the number of executors that are still running
+// after their worker gave up on them
+func (s *TaskService) AbandonedExecutors() int {
+	return int(s.abandoned.Load())
+}
+
+// failureReason classifies the error a task finally failed with
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrTaskTimedOut):
+		return model.FailureReasonTimedOut
+	case errors.Is(err, ErrDependencyFailed):
+		return model.FailureReasonDependencyFailed
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestTaskTimeout(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	release := make(chan struct{})
+
+	config := DefaultConfig()
+	config.Executors = map[string]TaskExecutor{
+		// Исполнитель игнорирует контекст, зависает и меняет свою копию задачи
+		DefaultTaskType: ExecutorFunc(func(_ context.Context, task *model.Task) (json.RawMessage, error) {
+			<-release
+			task.Title = "Changed"
+			return TextResult("too late"), nil
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		Timeout:     "50ms",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, task.Timeout)
+
+	task = waitForStatus(t, repo, task.ID, model.StatusFailed)
+	assert.Equal(t, model.FailureReasonTimedOut, task.FailureReason)
+	assert.Contains(t, task.Error, ErrTaskTimedOut.Error())
+	assert.Empty(t, task.Result)
+	assert.Equal(t, 1, service.AbandonedExecutors())
+
+	// Брошенный исполнитель дорабатывает, не трогая сохранённую задачу
+	close(release)
+	assert.Eventually(t, func() bool { return service.AbandonedExecutors() == 0 }, time.Second, 5*time.Millisecond)
+	task, err = repo.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Task", task.Title)
+	assert.Equal(t, model.StatusFailed, task.Status)
+}
+
+func TestAbandonedExecutorLimit(t *testing.T) {
+	service, _ := newBlockingService(t)
+
+	// Когда брошенных исполнителей слишком много, воркер ждёт возврата исполнителя
+	service.abandoned.Store(maxAbandonedExecutors)
+	defer service.abandoned.Store(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	returned := false
+	executor := ExecutorFunc(func(_ context.Context, _ *model.Task) (json.RawMessage, error) {
+		time.Sleep(50 * time.Millisecond)
+		returned = true
+		return TextResult("done"), nil
+	})
+
+	_, err := service.execute(ctx, executor, model.NewTask("Task", "Description", DefaultTaskType, nil))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, returned)
+	assert.Equal(t, maxAbandonedExecutors, service.AbandonedExecutors())
+}
+
+func TestInvalidTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.Timeout.Max = time.Minute
+	service := NewTaskService(repository.NewTaskRepository(), setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	for _, timeout := range []string{"soon", "-1s", "2m"} {
+		_, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+			Title:       "Task",
+			Description: "Description",
+			Timeout:     timeout,
+		})
+		assert.ErrorIs(t, err, ErrInvalidTimeout, timeout)
+	}
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, config.Timeout.Default, task.Timeout)
+}
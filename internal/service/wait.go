@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// Limits of how long WaitTask may wait
+const (
+	DefaultWaitTimeout = 30 * time.Second
+	MaxWaitTimeout     = 5 * time.Minute
+)
+
+var (
+	ErrInvalidWaitTimeout = errors.New("invalid wait timeout")
+	ErrWaitTimedOut       = errors.New("task did not finish in time")
+)
+
+// WaitTask waits until the task reaches a terminal status and returns it.
+// If the timeout expires first, it returns the task as it is with
+// ErrWaitTimedOut. Waiters are woken by the task's result event rather than
+// polling the repository; a waiter that falls behind the event bus reads the
+// task again and keeps waiting. If the service shuts down first, it returns
+// ErrServiceStopped.
+func (s *TaskService) WaitTask(ctx context.Context, id string, timeout time.Duration) (*model.Task, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidTaskID
+	}
+	if timeout <= 0 || timeout > MaxWaitTimeout {
+		return nil, errors.Wrapf(ErrInvalidWaitTimeout, "timeout must be positive and at most %s", MaxWaitTimeout)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		task, done, err := s.waitTaskEvent(ctx, id, timer.C)
+		if done || err != nil {
+			return task, err
+		}
+	}
+}
+
+// waitTaskEvent reads the task and, unless it has finished, waits for its
+// result event. It reports done once the task has finished or the wait is
+// over; otherwise the caller reads the task again.
+func (s *TaskService) waitTaskEvent(ctx context.Context, id string, timeout <-chan time.Time) (*model.Task, bool, error) {
+	// Subscribe before reading the task, so a result published in between is not missed
+	sub := s.SubscribeEvents(EventFilter{TaskID: id, Events: []EventType{EventResult}}, 0)
+	defer sub.Close()
+
+	task, err := s.repo.GetTask(id)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "get task")
+	}
+	if task.Status.IsTerminal() {
+		return task, true, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, true, errors.Wrap(ctx.Err(), "wait for task")
+	case <-timeout:
+		task, err = s.GetTask(id)
+		if err != nil {
+			return nil, true, err
+		}
+		if !task.Status.IsTerminal() {
+			return task, true, ErrWaitTimedOut
+		}
+		return task, true, nil
+	case _, ok := <-sub.C:
+		if !ok && !sub.Lagged() {
+			return nil, true, ErrServiceStopped
+		}
+		// Either the result arrived or the waiter fell behind and may have missed it
+		return nil, false, nil
+	}
+}
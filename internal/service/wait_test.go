@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+func TestWaitTask(t *testing.T) {
+	repo := repository.NewTaskRepository()
+
+	release := make(chan struct{})
+	config := DefaultConfig()
+	config.Events.SubscriberBuffer = 1
+	config.Executors = map[string]TaskExecutor{
+		DefaultTaskType: ExecutorFunc(func(ctx context.Context, _ *model.Task) (json.RawMessage, error) {
+			select {
+			case <-release:
+				return TextResult("done"), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}),
+	}
+	service := NewTaskService(repo, setupTestLogger(), config)
+	defer service.Shutdown(context.Background())
+
+	task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+	require.NoError(t, err)
+	waitForStatus(t, repo, task.ID, model.StatusProcessing)
+
+	t.Run("timeout", func(t *testing.T) {
+		got, err := service.WaitTask(context.Background(), task.ID, 50*time.Millisecond)
+		assert.ErrorIs(t, err, ErrWaitTimedOut)
+		require.NotNil(t, got)
+		assert.Equal(t, model.StatusProcessing, got.Status)
+	})
+
+	t.Run("lagged", func(t *testing.T) {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			// Лишние события переполняют буфер подписчика
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(5 * time.Millisecond):
+					service.events.Publish(EventResult, task)
+				}
+			}
+		}()
+
+		started := time.Now()
+		got, err := service.WaitTask(context.Background(), task.ID, 200*time.Millisecond)
+		assert.ErrorIs(t, err, ErrWaitTimedOut)
+		require.NotNil(t, got)
+		assert.Equal(t, model.StatusProcessing, got.Status)
+		// Ожидание не обрывается раньше таймаута
+		assert.GreaterOrEqual(t, time.Since(started), 200*time.Millisecond)
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+
+		started := time.Now()
+		got, err := service.WaitTask(context.Background(), task.ID, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCompleted, got.Status)
+		assert.Less(t, time.Since(started), time.Second)
+
+		// Завершённая задача возвращается сразу
+		got, err = service.WaitTask(context.Background(), task.ID, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusCompleted, got.Status)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := service.WaitTask(context.Background(), task.ID, time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidWaitTimeout)
+
+		_, err = service.WaitTask(context.Background(), "42", time.Second)
+		assert.ErrorIs(t, err, repository.ErrTaskNotFound)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		pending, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Later", Description: "Description", Delay: "1h"})
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			service.events.Close()
+		}()
+
+		_, err = service.WaitTask(context.Background(), pending.ID, time.Minute)
+		assert.ErrorIs(t, err, ErrServiceStopped)
+	})
+}
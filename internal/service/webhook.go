@@ -0,0 +1,621 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+	"github.com/nessibeliyeltay/task-api/pkg/logger"
+)
+
+var (
+	ErrInvalidWebhookID      = errors.New("invalid webhook ID format")
+	ErrInvalidWebhookURL     = errors.New("invalid webhook URL")
+	ErrInvalidWebhookEvent   = errors.New("invalid webhook event")
+	ErrInvalidCallbackURL    = errors.New("invalid callback URL")
+	ErrInsecureWebhookSecret = errors.New("webhook secret is empty or the placeholder from the sample config")
+)
+
+// PlaceholderWebhookSecret is the secret of the sample config. Deliveries
+// signed with it could be forged by anyone, so it is refused like an empty one.
+const PlaceholderWebhookSecret = "change-me"
+
+// deliveryPruneInterval is how often finished deliveries are pruned
+const deliveryPruneInterval = time.Minute
+
+// Headers sent with every webhook delivery. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the configured
+// secret, prefixed with "sha256=".
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookConfig holds configuration of webhook deliveries
+type WebhookConfig struct {
+	// Secret is the key deliveries are signed with
+	Secret string
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is given up
+	MaxAttempts int
+	// Backoff is the delay between attempts of a delivery
+	Backoff model.BackoffPolicy
+	// Workers is the number of deliveries sent at the same time
+	Workers int
+	// DeliveryRetention is how long finished deliveries are kept. 0 keeps
+	// them regardless of age.
+	DeliveryRetention time.Duration
+	// MaxDeliveries is how many finished deliveries are kept. 0 means no limit.
+	MaxDeliveries int
+	// AllowPrivateTargets lets webhooks and callback URLs point to loopback,
+	// link-local and private network addresses
+	AllowPrivateTargets bool
+}
+
+// EventSource publishes the task events webhooks are sent for
+type EventSource interface {
+	SubscribeEvents(filter EventFilter, lastID uint64) *Subscription
+}
+
+type WebhookServiceInterface interface {
+	CreateWebhook(req dto.CreateWebhookRequest) (*model.WebhookSubscription, error)
+	ListWebhooks() ([]*model.WebhookSubscription, error)
+	DeleteWebhook(id string) error
+	ListDeliveries(taskID string) ([]*model.WebhookDelivery, error)
+	Shutdown(ctx context.Context) error
+}
+
+// WebhookService posts task events to the callback URL of the task and to the
+// webhook subscriptions interested in them. Failed deliveries are retried
+// with backoff.
+type WebhookService struct {
+	repo   repository.WebhookRepositoryInterface
+	events EventSource
+	logger *logger.Logger
+	config WebhookConfig
+	client *http.Client
+	// retries holds the deliveries waiting for their next attempt
+	retries      *delayQueue
+	pending      chan string
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	shutdownChan chan struct{}
+}
+
+// NewWebhookService starts sending webhooks. It refuses to start with
+// ErrInsecureWebhookSecret unless a secret of its own is configured.
+func NewWebhookService(repo repository.WebhookRepositoryInterface, events EventSource, logger *logger.Logger, config WebhookConfig) (*WebhookService, error) {
+	if config.Secret == "" || config.Secret == PlaceholderWebhookSecret {
+		return nil, ErrInsecureWebhookSecret
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service := &WebhookService{
+		repo:         repo,
+		events:       events,
+		logger:       logger,
+		config:       config,
+		client:       newWebhookClient(config),
+		retries:      newDelayQueue(),
+		pending:      make(chan string),
+		ctx:          ctx,
+		cancel:       cancel,
+		shutdownChan: make(chan struct{}),
+	}
+
+	// Subscribe before returning, so no event published after that is missed
+	sub := events.SubscribeEvents(webhookEventFilter, 0)
+	service.resumeDeliveries()
+
+	service.wg.Add(3 + config.Workers)
+	go service.consumeEvents(sub)
+	go service.runRetries()
+	go service.runPruner()
+	for i := 0; i < config.Workers; i++ {
+		go service.runWorker()
+	}
+
+	return service, nil
+}
+
+// checkWebhookURL checks that raw is an absolute http or https URL and, unless
+// allowPrivate is set, that it does not name a private network address. It
+// wraps invalid with the reason if the URL is refused.
+func checkWebhookURL(raw string, invalid error, allowPrivate bool) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.Wrapf(invalid, "%q is not an absolute http or https URL", raw)
+	}
+	if !allowPrivate && isPrivateHost(parsed.Hostname()) {
+		return errors.Wrapf(invalid, "%q points to a private network address", raw)
+	}
+	return nil
+}
+
+// isPrivateHost reports whether host is localhost or a private network address
+func isPrivateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(host)
+	return err == nil && isPrivateAddr(ip)
+}
+
+// isPrivateAddr reports whether ip is a loopback, link-local, private or
+// unspecified address, which webhooks must not reach unless allowed to
+func isPrivateAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// errPrivateTarget fails a delivery that would connect to a private network address
+var errPrivateTarget = errors.New("webhook target is a private network address")
+
+// newWebhookClient returns the client deliveries are sent with. Unless private
+// targets are allowed, it checks every address it connects to, which also
+// covers host names resolving to a private address and redirects to one.
+func newWebhookClient(config WebhookConfig) *http.Client {
+	client := &http.Client{Timeout: config.Timeout}
+	if config.AllowPrivateTargets {
+		return client
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.Wrap(err, "parse address")
+			}
+			if ip, err := netip.ParseAddr(host); err == nil && isPrivateAddr(ip) {
+				return errPrivateTarget
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	// Connect directly, so the address checked is the one the delivery goes to
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	client.Transport = transport
+	return client
+}
+
+// defaultWebhookEvents are sent to subscriptions that do not list any events
+var defaultWebhookEvents = []string{
+	model.WebhookEvent(model.StatusCompleted),
+	model.WebhookEvent(model.StatusFailed),
+	model.WebhookEvent(model.StatusCancelled),
+	model.WebhookEvent(model.StatusDeadLetter),
+}
+
+// webhookEvents are the events a subscription may list
+var webhookEvents = map[string]struct{}{
+	model.WebhookEvent(model.StatusScheduled):  {},
+	model.WebhookEvent(model.StatusBlocked):    {},
+	model.WebhookEvent(model.StatusPending):    {},
+	model.WebhookEvent(model.StatusProcessing): {},
+	model.WebhookEvent(model.StatusCompleted):  {},
+	model.WebhookEvent(model.StatusFailed):     {},
+	model.WebhookEvent(model.StatusCancelled):  {},
+	model.WebhookEvent(model.StatusDeadLetter): {},
+}
+
+func (s *WebhookService) CreateWebhook(req dto.CreateWebhookRequest) (*model.WebhookSubscription, error) {
+	if err := checkWebhookURL(req.URL, ErrInvalidWebhookURL, s.config.AllowPrivateTargets); err != nil {
+		return nil, err
+	}
+
+	events := dedupe(req.Events)
+	for _, event := range events {
+		if _, ok := webhookEvents[event]; !ok {
+			return nil, errors.Wrapf(ErrInvalidWebhookEvent, "%q", event)
+		}
+	}
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+
+	webhook, err := s.repo.CreateWebhook(&model.WebhookSubscription{
+		URL:       req.URL,
+		Events:    events,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create webhook")
+	}
+
+	s.logger.Info("Webhook created",
+		zap.String("webhook_id", webhook.ID),
+		zap.String("url", webhook.URL),
+		zap.Strings("events", webhook.Events))
+
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks() ([]*model.WebhookSubscription, error) {
+	return s.repo.ListWebhooks() //nolint:wrapcheck
+}
+
+func (s *WebhookService) DeleteWebhook(id string) error {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return ErrInvalidWebhookID
+	}
+
+	if err := s.repo.DeleteWebhook(id); err != nil {
+		return errors.Wrap(err, "delete webhook")
+	}
+
+	s.logger.Info("Webhook deleted", zap.String("webhook_id", id))
+	return nil
+}
+
+// ListDeliveries returns the deliveries of a task's events with their attempts
+func (s *WebhookService) ListDeliveries(taskID string) ([]*model.WebhookDelivery, error) {
+	if _, err := strconv.ParseInt(taskID, 10, 64); err != nil {
+		return nil, ErrInvalidTaskID
+	}
+
+	return s.repo.ListDeliveries(taskID) //nolint:wrapcheck
+}
+
+// webhookEventFilter selects the events webhooks may be sent for
+var webhookEventFilter = EventFilter{Events: []EventType{EventStatus, EventResult}}
+
+// consumeEvents turns task events into deliveries. If it falls behind the
+// event bus it resumes from the last event it handled.
+func (s *WebhookService) consumeEvents(sub *Subscription) {
+	defer s.wg.Done()
+
+	lastID := sub.LastID
+	for {
+		for _, event := range sub.Replay {
+			s.handleEvent(event)
+			lastID = event.ID
+		}
+
+		for open := true; open; {
+			select {
+			case <-s.shutdownChan:
+				sub.Close()
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					open = false
+					break
+				}
+				s.handleEvent(event)
+				lastID = event.ID
+			}
+		}
+
+		if !sub.Lagged() {
+			return
+		}
+		s.logger.Warn("Webhook deliveries fell behind task events, resuming",
+			zap.Uint64("last_event_id", lastID))
+		sub = s.events.SubscribeEvents(webhookEventFilter, lastID)
+	}
+}
+
+// webhookTarget is a URL an event is delivered to
+type webhookTarget struct {
+	url            string
+	subscriptionID string
+}
+
+// handleEvent creates a delivery of the event for the task's callback URL,
+// once the task has finished, and for every subscription listing the event
+func (s *WebhookService) handleEvent(event Event) {
+	task := event.Task
+	name := model.WebhookEvent(task.Status)
+
+	var targets []webhookTarget
+	if event.Type == EventResult && task.CallbackURL != "" {
+		targets = append(targets, webhookTarget{url: task.CallbackURL})
+	}
+
+	webhooks, err := s.repo.ListWebhooks()
+	if err != nil {
+		s.logger.Error("Failed to list webhooks", err)
+	}
+	for _, webhook := range webhooks {
+		if matchAny(webhook.Events, name) {
+			targets = append(targets, webhookTarget{url: webhook.URL, subscriptionID: webhook.ID})
+		}
+	}
+
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(dto.NewTaskResponse(task))
+	if err != nil {
+		s.logger.Error("Failed to encode webhook payload", err, zap.String("task_id", task.ID))
+		return
+	}
+
+	now := time.Now()
+	for _, target := range targets {
+		delivery, err := s.repo.CreateDelivery(&model.WebhookDelivery{
+			TaskID:         task.ID,
+			SubscriptionID: target.subscriptionID,
+			URL:            target.url,
+			Event:          name,
+			Payload:        payload,
+			Status:         model.DeliveryPending,
+			CreatedAt:      now,
+			NextAttemptAt:  &now,
+		})
+		if err != nil {
+			s.logger.Error("Failed to create webhook delivery", err,
+				zap.String("task_id", task.ID),
+				zap.String("url", target.url))
+			continue
+		}
+		s.retries.Add(delivery.ID, now)
+	}
+}
+
+// resumeDeliveries schedules the deliveries a previous run left pending
+func (s *WebhookService) resumeDeliveries() {
+	deliveries, err := s.repo.ListPendingDeliveries()
+	if err != nil {
+		s.logger.Error("Failed to list pending webhook deliveries", err)
+		return
+	}
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		due := now
+		if delivery.NextAttemptAt != nil {
+			due = *delivery.NextAttemptAt
+		}
+		s.retries.Add(delivery.ID, due)
+	}
+
+	if len(deliveries) > 0 {
+		s.logger.Info("Resuming pending webhook deliveries", zap.Int("deliveries", len(deliveries)))
+	}
+}
+
+// runRetries hands deliveries to the workers when their next attempt is due
+func (s *WebhookService) runRetries() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-s.retries.wake:
+		case <-timer.C:
+		}
+
+		ids, wait := s.retries.due(time.Now())
+		for _, id := range ids {
+			select {
+			case s.pending <- id:
+			case <-s.shutdownChan:
+				return
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// runPruner drops old finished deliveries, so the delivery log does not grow
+// without bound
+func (s *WebhookService) runPruner() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(deliveryPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-ticker.C:
+			s.pruneDeliveries()
+		}
+	}
+}
+
+func (s *WebhookService) pruneDeliveries() {
+	if s.config.DeliveryRetention <= 0 && s.config.MaxDeliveries <= 0 {
+		return
+	}
+
+	var before time.Time
+	if s.config.DeliveryRetention > 0 {
+		before = time.Now().Add(-s.config.DeliveryRetention)
+	}
+
+	removed, err := s.repo.PruneDeliveries(before, s.config.MaxDeliveries)
+	if err != nil {
+		s.logger.Error("Failed to prune webhook deliveries", err)
+		return
+	}
+	if removed > 0 {
+		s.logger.Info("Pruned webhook deliveries", zap.Int("removed", removed))
+	}
+}
+
+func (s *WebhookService) runWorker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.shutdownChan:
+			return
+		case id := <-s.pending:
+			s.attempt(id)
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome. A failed attempt is
+// retried after a backoff delay until the delivery runs out of attempts.
+func (s *WebhookService) attempt(id string) {
+	delivery, err := s.repo.GetDelivery(id)
+	if err != nil {
+		s.logger.Error("Failed to get webhook delivery", err, zap.String("delivery_id", id))
+		return
+	}
+	if delivery.Status != model.DeliveryPending {
+		return
+	}
+
+	record := model.DeliveryAttempt{Attempt: len(delivery.Attempts) + 1, SentAt: time.Now()}
+	record.StatusCode, err = s.send(delivery)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			// Interrupted by shutdown, the attempt does not count
+			return
+		}
+		record.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, record)
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = model.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case record.Attempt >= s.config.MaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(backoffDelay(s.config.Backoff, record.Attempt))
+		delivery.NextAttemptAt = &next
+	}
+
+	if _, err := s.repo.UpdateDelivery(delivery); err != nil {
+		s.logger.Error("Failed to update webhook delivery", err, zap.String("delivery_id", id))
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("delivery_id", delivery.ID),
+		zap.String("task_id", delivery.TaskID),
+		zap.String("event", delivery.Event),
+		zap.String("url", delivery.URL),
+		zap.Int("attempt", record.Attempt),
+	}
+	switch delivery.Status {
+	case model.DeliveryDelivered:
+		s.logger.Info("Webhook delivered", fields...)
+	case model.DeliveryFailed:
+		s.logger.Warn("Webhook delivery failed", append(fields, zap.String("error", record.Error))...)
+	default:
+		s.logger.Info("Webhook delivery attempt failed, retrying",
+			append(fields, zap.String("error", record.Error), zap.Time("next_attempt_at", *delivery.NextAttemptAt))...)
+		s.retries.Add(delivery.ID, *delivery.NextAttemptAt)
+	}
+}
+
+// send posts the delivery's payload. It returns the response status code, if
+// there was a response, and an error unless the status code was 2xx.
+func (s *WebhookService) send(delivery *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "create request")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(s.config.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "send request")
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex encoded signature of a webhook body sent at the
+// given timestamp
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Shutdown stops sending webhooks. Deliveries that are still pending are
+// resumed by the next service started on the same repository.
+func (s *WebhookService) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down webhook service")
+	close(s.shutdownChan)
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "ctx done")
+	case <-done:
+		return nil
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+// webhookReceiver записывает полученные вебхуки и отвечает ошибкой на первые failures запросов
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newTestWebhookService разрешает доставку на локальные адреса тестовых серверов
+func newTestWebhookService(t *testing.T) (*WebhookService, *TaskService) {
+	t.Helper()
+
+	taskConfig := DefaultConfig()
+	taskConfig.Webhooks.AllowPrivateTargets = true
+	tasks := newInstantService(t, repository.NewTaskRepository(), taskConfig)
+
+	config := taskConfig.Webhooks
+	config.Secret = "secret"
+	config.MaxAttempts = 3
+	config.Backoff = model.BackoffPolicy{Initial: 10 * time.Millisecond, Multiplier: 1}
+
+	service, err := NewWebhookService(repository.NewWebhookRepository(), tasks, setupTestLogger(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+	return service, tasks
+}
+
+// waitForDeliveries ждёт, пока все доставки задачи завершатся
+func waitForDeliveries(t *testing.T, service *WebhookService, taskID string, count int) []*model.WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := service.ListDeliveries(taskID)
+		require.NoError(t, err)
+
+		finished := 0
+		for _, delivery := range deliveries {
+			if delivery.Status != model.DeliveryPending {
+				finished++
+			}
+		}
+		if len(deliveries) == count && finished == count {
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.FailNow(t, "deliveries did not finish")
+	return nil
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	service, tasks := newTestWebhookService(t)
+
+	subscriber := &webhookReceiver{}
+	subscriberServer := httptest.NewServer(subscriber)
+	defer subscriberServer.Close()
+
+	// Обработчик callback_url отвечает ошибкой на первый запрос
+	callback := &webhookReceiver{failures: 1}
+	callbackServer := httptest.NewServer(callback)
+	defer callbackServer.Close()
+
+	webhook, err := service.CreateWebhook(dto.CreateWebhookRequest{URL: subscriberServer.URL})
+	require.NoError(t, err)
+	assert.Contains(t, webhook.Events, "task.completed")
+
+	task, err := tasks.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		CallbackURL: callbackServer.URL,
+	})
+	require.NoError(t, err)
+
+	deliveries := waitForDeliveries(t, service, task.ID, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(t, model.DeliveryDelivered, delivery.Status)
+		assert.Equal(t, "task.completed", delivery.Event)
+	}
+
+	toCallback := deliveries[0]
+	if toCallback.SubscriptionID != "" {
+		toCallback = deliveries[1]
+	}
+	require.Len(t, toCallback.Attempts, 2)
+	assert.Equal(t, http.StatusInternalServerError, toCallback.Attempts[0].StatusCode)
+	assert.NotEmpty(t, toCallback.Attempts[0].Error)
+	assert.Equal(t, http.StatusNoContent, toCallback.Attempts[1].StatusCode)
+
+	subscriber.mu.Lock()
+	defer subscriber.mu.Unlock()
+	require.Len(t, subscriber.requests, 1)
+
+	req, body := subscriber.requests[0], subscriber.bodies[0]
+	assert.Equal(t, "task.completed", req.Header.Get(WebhookEventHeader))
+	timestamp := req.Header.Get(WebhookTimestampHeader)
+	assert.Equal(t, "sha256="+SignWebhook("secret", timestamp, body), req.Header.Get(WebhookSignatureHeader))
+
+	var resp dto.TaskResponse
+	require.NoError(t, json.Unmarshal(body, &resp))
+	assert.Equal(t, task.ID, resp.ID)
+	assert.Equal(t, string(model.StatusCompleted), resp.Status)
+}
+
+func TestWebhookDeliveryFails(t *testing.T) {
+	service, tasks := newTestWebhookService(t)
+
+	receiver := &webhookReceiver{failures: 10}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	task, err := tasks.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		CallbackURL: server.URL,
+	})
+	require.NoError(t, err)
+
+	deliveries := waitForDeliveries(t, service, task.ID, 1)
+	assert.Equal(t, model.DeliveryFailed, deliveries[0].Status)
+	assert.Len(t, deliveries[0].Attempts, 3)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+}
+
+func TestWebhookDeliveryResumed(t *testing.T) {
+	tasks := newInstantService(t, repository.NewTaskRepository(), DefaultConfig())
+
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// Доставка, оставшаяся ожидающей после предыдущего запуска
+	repo := repository.NewWebhookRepository()
+	now := time.Now()
+	delivery, err := repo.CreateDelivery(&model.WebhookDelivery{
+		TaskID:        "1",
+		URL:           server.URL,
+		Event:         "task.completed",
+		Payload:       json.RawMessage(`{"id":"1"}`),
+		Status:        model.DeliveryPending,
+		CreatedAt:     now,
+		NextAttemptAt: &now,
+	})
+	require.NoError(t, err)
+
+	config := DefaultConfig().Webhooks
+	config.Secret = "secret"
+	config.AllowPrivateTargets = true
+	service, err := NewWebhookService(repo, tasks, setupTestLogger(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+	deliveries := waitForDeliveries(t, service, delivery.TaskID, 1)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.Len(t, deliveries[0].Attempts, 1)
+}
+
+func TestWebhookEventsFilter(t *testing.T) {
+	service, tasks := newTestWebhookService(t)
+
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	_, err := service.CreateWebhook(dto.CreateWebhookRequest{URL: server.URL, Events: []string{"task.processing"}})
+	require.NoError(t, err)
+
+	task, err := tasks.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description"})
+	require.NoError(t, err)
+
+	// Подписка получает только событие начала обработки
+	deliveries := waitForDeliveries(t, service, task.ID, 1)
+	assert.Equal(t, "task.processing", deliveries[0].Event)
+}
+
+func TestInvalidWebhook(t *testing.T) {
+	service, tasks := newTestWebhookService(t)
+
+	_, err := service.CreateWebhook(dto.CreateWebhookRequest{URL: "ftp://example.com"})
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+
+	_, err = service.CreateWebhook(dto.CreateWebhookRequest{URL: "http://example.com", Events: []string{"task.exploded"}})
+	assert.ErrorIs(t, err, ErrInvalidWebhookEvent)
+
+	_, err = tasks.CreateTask(context.Background(), dto.CreateTaskRequest{
+		Title:       "Task",
+		Description: "Description",
+		CallbackURL: "/relative",
+	})
+	assert.ErrorIs(t, err, ErrInvalidCallbackURL)
+
+	err = service.DeleteWebhook("42")
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+}
+
+func TestWebhookPrivateTargets(t *testing.T) {
+	tasks := newInstantService(t, repository.NewTaskRepository(), DefaultConfig())
+
+	config := DefaultConfig().Webhooks
+	config.Secret = "secret"
+	service, err := NewWebhookService(repository.NewWebhookRepository(), tasks, setupTestLogger(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { service.Shutdown(context.Background()) })
+
+	for _, url := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		_, err := service.CreateWebhook(dto.CreateWebhookRequest{URL: url})
+		assert.ErrorIs(t, err, ErrInvalidWebhookURL, url)
+
+		_, err = tasks.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Task", Description: "Description", CallbackURL: url})
+		assert.ErrorIs(t, err, ErrInvalidCallbackURL, url)
+	}
+
+	_, err = service.CreateWebhook(dto.CreateWebhookRequest{URL: "https://example.com/hook"})
+	assert.NoError(t, err)
+
+	// Адрес проверяется и при соединении, так что имена хостов и перенаправления не обходят запрет
+	server := httptest.NewServer(&webhookReceiver{})
+	defer server.Close()
+	_, err = newWebhookClient(config).Post(server.URL, "application/json", nil)
+	assert.ErrorIs(t, err, errPrivateTarget)
+}
+
+func TestWebhookSecretRequired(t *testing.T) {
+	tasks := newInstantService(t, repository.NewTaskRepository(), DefaultConfig())
+
+	// Без собственного секрета подпись можно подделать, поэтому вебхуки не запускаются
+	for _, secret := range []string{"", PlaceholderWebhookSecret} {
+		config := DefaultConfig().Webhooks
+		config.Secret = secret
+		_, err := NewWebhookService(repository.NewWebhookRepository(), tasks, setupTestLogger(), config)
+		assert.ErrorIs(t, err, ErrInsecureWebhookSecret)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+// DefaultWorkerCount is the number of workers the service starts with
+const DefaultWorkerCount = 5
+
+// MaxWorkerCount is the largest worker pool the service can be resized to
+const MaxWorkerCount = 1000
+
+var (
+	ErrInvalidWorkerCount = errors.New("invalid worker count")
+	ErrServiceStopped     = errors.New("task service is shutting down")
+)
+
+// WorkerPoolInterface manages the workers that run tasks
+type WorkerPoolInterface interface {
+	Workers() []*model.Worker
+	ResizeWorkers(count int) error
+}
+
+// worker is a goroutine that takes tasks from the queue and runs them
+type worker struct {
+	id        int
+	startedAt time.Time
+	// quit is closed to retire the worker
+	quit     chan struct{}
+	retiring bool
+	// task is the task the worker runs, if any
+	task          string
+	taskStartedAt time.Time
+	processed     int
+}
+
+// workerPool keeps track of the running workers. Retired workers leave the
+// pool once their loop has exited, after finishing their current task.
+type workerPool struct {
+	mu      sync.Mutex
+	workers map[int]*worker
+	nextID  int
+}
+
+func newWorkerPool() *workerPool {
+	return &workerPool{workers: make(map[int]*worker)}
+}
+
+// active counts the workers that are not retiring
+func (p *workerPool) active() int {
+	n := 0
+	for _, w := range p.workers {
+		if !w.retiring {
+			n++
+		}
+	}
+	return n
+}
+
+// busy counts the workers running a task that are not retiring
+func (p *workerPool) busy() int {
+	n := 0
+	for _, w := range p.workers {
+		if !w.retiring && w.task != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *workerPool) begin(w *worker, taskID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w.task = taskID
+	w.taskStartedAt = time.Now()
+}
+
+func (p *workerPool) end(w *worker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w.task = ""
+	w.processed++
+}
+
+func (p *workerPool) remove(w *worker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.workers, w.id)
+}
+
+// SetWorkerCount resizes the worker pool, keeping at least one worker
+func (s *TaskService) SetWorkerCount(count int) {
+	if err := s.ResizeWorkers(min(max(count, 1), MaxWorkerCount)); err != nil {
+		s.logger.Error("Failed to resize worker pool", err, zap.Int("count", count))
+	}
+}
+
+// WorkerCount returns the number of workers that are not retiring
+func (s *TaskService) WorkerCount() int {
+	s.workers.mu.Lock()
+	defer s.workers.mu.Unlock()
+
+	return s.workers.active()
+}
+
+// BusyWorkers returns the number of workers running a task, not counting
+// retiring workers
+func (s *TaskService) BusyWorkers() int {
+	s.workers.mu.Lock()
+	defer s.workers.mu.Unlock()
+
+	return s.workers.busy()
+}
+
+// QueueDepth returns the number of tasks waiting for a worker
+func (s *TaskService) QueueDepth() int {
+	return s.queue.Len()
+}
+
+// OldestTaskWait returns how long the longest waiting task has waited for a worker
+func (s *TaskService) OldestTaskWait() time.Duration {
+	return s.queue.OldestWait()
+}
+
+// ResizeWorkers grows or shrinks the worker pool to count workers. New
+// workers start right away. Idle workers are retired first; if that is not
+// enough, busy workers are retired once they finish their current task.
+func (s *TaskService) ResizeWorkers(count int) error {
+	if count < 1 || count > MaxWorkerCount {
+		return errors.Wrapf(ErrInvalidWorkerCount, "count must be between 1 and %d", MaxWorkerCount)
+	}
+
+	s.workers.mu.Lock()
+	defer s.workers.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return ErrServiceStopped
+	}
+
+	previous := s.workers.active()
+	for i := previous; i < count; i++ {
+		s.startWorker()
+	}
+
+	if count < previous {
+		candidates := make([]*worker, 0, len(s.workers.workers))
+		for _, w := range s.workers.workers {
+			if !w.retiring {
+				candidates = append(candidates, w)
+			}
+		}
+		// Idle workers first, the most recently started first among equals
+		slices.SortFunc(candidates, func(a, b *worker) int {
+			if (a.task == "") != (b.task == "") {
+				if a.task == "" {
+					return -1
+				}
+				return 1
+			}
+			return b.id - a.id
+		})
+
+		// Retired workers stay in the pool, marked as retiring, until their
+		// loop has exited
+		for _, w := range candidates[:previous-count] {
+			w.retiring = true
+			close(w.quit)
+		}
+	}
+
+	if count != previous {
+		s.logger.Info("Worker pool resized",
+			zap.Int("from", previous),
+			zap.Int("to", count))
+	}
+	return nil
+}
+
+// Workers returns a snapshot of the workers, ordered by ID
+func (s *TaskService) Workers() []*model.Worker {
+	s.workers.mu.Lock()
+	defer s.workers.mu.Unlock()
+
+	workers := make([]*model.Worker, 0, len(s.workers.workers))
+	for _, w := range s.workers.workers {
+		info := &model.Worker{
+			ID:             w.id,
+			State:          model.WorkerIdle,
+			StartedAt:      w.startedAt,
+			TasksProcessed: w.processed,
+			Retiring:       w.retiring,
+		}
+		if w.task != "" {
+			startedAt := w.taskStartedAt
+			info.State = model.WorkerBusy
+			info.TaskID = w.task
+			info.TaskStartedAt = &startedAt
+		}
+		workers = append(workers, info)
+	}
+
+	slices.SortFunc(workers, func(a, b *model.Worker) int { return a.ID - b.ID })
+	return workers
+}
+
+// startWorker adds a worker to the pool. The caller holds the pool lock.
+func (s *TaskService) startWorker() {
+	w := &worker{
+		id:        s.workers.nextID,
+		startedAt: time.Now(),
+		quit:      make(chan struct{}),
+	}
+	s.workers.nextID++
+	s.workers.workers[w.id] = w
+
+	s.wg.Add(1)
+	go s.runWorker(s.ctx, w)
+}
+
+// runWorker takes tasks from the queue until the worker is retired or the
+// service shuts down
+func (s *TaskService) runWorker(ctx context.Context, w *worker) {
+	defer s.wg.Done()
+	defer s.workers.remove(w)
+
+	s.logger.Info("Worker started", zap.Int("worker_id", w.id))
+
+	for {
+		task, ok := s.queue.Pop(w.quit)
+		if !ok {
+			select {
+			case <-w.quit:
+				s.logger.Info("Worker retired", zap.Int("worker_id", w.id))
+			default:
+				s.logger.Info("Worker stopping due to shutdown signal", zap.Int("worker_id", w.id))
+			}
+			return
+		}
+
+		s.workers.begin(w, task.ID)
+		processed := s.processTask(ctx, task)
+		s.workers.end(w)
+		if !processed {
+			return
+		}
+	}
+}
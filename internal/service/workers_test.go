@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+)
+
+func workerStates(service *TaskService) map[model.WorkerState]int {
+	states := make(map[model.WorkerState]int)
+	for _, worker := range service.Workers() {
+		states[worker.State]++
+	}
+	return states
+}
+
+func TestResizeWorkers(t *testing.T) {
+	service, repo := newBlockingService(t)
+	require.Equal(t, DefaultWorkerCount, service.WorkerCount())
+
+	var tasks []*model.Task
+	for i := 0; i < 2; i++ {
+		task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Busy", Description: "Description"})
+		require.NoError(t, err)
+		tasks = append(tasks, waitForStatus(t, repo, task.ID, model.StatusProcessing))
+	}
+
+	t.Run("shrink idle", func(t *testing.T) {
+		// Сначала останавливаются свободные воркеры
+		require.NoError(t, service.ResizeWorkers(2))
+		assert.Equal(t, 2, service.WorkerCount())
+		// Остановленные воркеры видны как уходящие, пока их цикл не завершится
+		for _, worker := range service.Workers() {
+			assert.True(t, worker.State == model.WorkerBusy || worker.Retiring)
+		}
+		assert.Eventually(t, func() bool {
+			return workerStates(service)[model.WorkerBusy] == 2 && len(service.Workers()) == 2
+		}, time.Second, 5*time.Millisecond)
+
+		for _, worker := range service.Workers() {
+			// Задача ещё выполняется и не считается обработанной
+			assert.Zero(t, worker.TasksProcessed)
+			assert.NotEmpty(t, worker.TaskID)
+			assert.NotNil(t, worker.TaskStartedAt)
+		}
+	})
+
+	t.Run("shrink busy", func(t *testing.T) {
+		// Занятый воркер дорабатывает задачу и только потом останавливается
+		require.NoError(t, service.ResizeWorkers(1))
+		assert.Equal(t, 1, service.WorkerCount())
+
+		workers := service.Workers()
+		require.Len(t, workers, 2)
+		var retiring *model.Worker
+		for _, worker := range workers {
+			if worker.Retiring {
+				retiring = worker
+			}
+		}
+		require.NotNil(t, retiring)
+		assert.Equal(t, model.WorkerBusy, retiring.State)
+
+		_, err := service.CancelTask(context.Background(), retiring.TaskID, "")
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return len(service.Workers()) == 1 }, time.Second, 5*time.Millisecond)
+		assert.Zero(t, service.Workers()[0].TasksProcessed)
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		require.NoError(t, service.ResizeWorkers(4))
+		assert.Equal(t, 4, service.WorkerCount())
+
+		// Новые воркеры сразу берут задачи из очереди
+		for i := 0; i < 3; i++ {
+			task, err := service.CreateTask(context.Background(), dto.CreateTaskRequest{Title: "Busy", Description: "Description"})
+			require.NoError(t, err)
+			waitForStatus(t, repo, task.ID, model.StatusProcessing)
+		}
+		assert.Equal(t, 4, workerStates(service)[model.WorkerBusy])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.ErrorIs(t, service.ResizeWorkers(0), ErrInvalidWorkerCount)
+		assert.ErrorIs(t, service.ResizeWorkers(MaxWorkerCount+1), ErrInvalidWorkerCount)
+		assert.Equal(t, 4, service.WorkerCount())
+	})
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/nessibeliyeltay/task-api/internal/dto"
+	"github.com/nessibeliyeltay/task-api/internal/model"
+	"github.com/nessibeliyeltay/task-api/internal/repository"
+)
+
+var (
+	ErrInvalidWorkflow  = errors.New("invalid workflow")
+	ErrDependencyCycle  = errors.New("dependency cycle")
+	ErrWorkflowNotFound = errors.New("workflow not found")
+)
+
+// CreateWorkflow creates the tasks of a workflow at once. Tasks refer to each
+// other by key, and the graph they form must not contain cycles. The tasks
+// are stored in a single repository write, so a failure leaves none of them
+// behind, and no task of the workflow starts before all of them are stored.
+func (s *TaskService) CreateWorkflow(ctx context.Context, req dto.CreateWorkflowRequest) (*model.Workflow, error) {
+	order, err := workflowOrder(req.Tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	workflow := &model.Workflow{ID: uuid.NewString()}
+
+	// Validate every task before storing any of them
+	tasks := make([]*model.Task, len(order))
+	for i, item := range order {
+		taskReq := item.CreateTaskRequest
+		taskReq.DependsOn = nil
+
+		task, err := s.newTask(taskReq)
+		if err != nil {
+			return nil, errors.Wrapf(err, "task %q", item.Key)
+		}
+		tasks[i] = task
+	}
+
+	ready := 0
+	for i, item := range order {
+		if len(item.DependsOn) == 0 && tasks[i].Status == model.StatusPending {
+			ready++
+		}
+	}
+	if err := s.admit(ready); err != nil {
+		return nil, err
+	}
+
+	// Dependencies refer to earlier tasks of the batch until they are stored
+	positions := make(map[string]int, len(order))
+	for i, item := range order {
+		task := tasks[i]
+		task.WorkflowID = workflow.ID
+		task.WorkflowKey = item.Key
+		for _, key := range item.DependsOn {
+			task.DependsOn = append(task.DependsOn, repository.BatchRef(positions[key]))
+		}
+		if len(task.DependsOn) > 0 {
+			task.Status = model.StatusBlocked
+		}
+		positions[item.Key] = i
+	}
+
+	created, err := s.repo.CreateTasks(tasks)
+	if err != nil {
+		s.queue.Unreserve(ready)
+		return nil, errors.Wrap(err, "create tasks")
+	}
+	workflow.Tasks = created
+
+	s.logger.Info("Workflow created",
+		zap.String("workflow_id", workflow.ID),
+		zap.Int("tasks", len(workflow.Tasks)))
+
+	for _, task := range workflow.Tasks {
+		s.dispatch(task)
+	}
+
+	return workflow, nil
+}
+
+// workflowOrder validates the keys of a workflow and sorts its tasks so that
+// every task comes after the tasks it depends on
+func workflowOrder(items []dto.WorkflowTaskRequest) ([]dto.WorkflowTaskRequest, error) {
+	byKey := make(map[string]dto.WorkflowTaskRequest, len(items))
+	for _, item := range items {
+		if _, ok := byKey[item.Key]; ok {
+			return nil, errors.Wrapf(ErrInvalidWorkflow, "duplicate key %q", item.Key)
+		}
+		byKey[item.Key] = item
+	}
+
+	// Kahn's algorithm: repeatedly take the tasks whose dependencies are all placed
+	waiting := make(map[string]int, len(items))
+	dependents := make(map[string][]string)
+	for _, item := range items {
+		seen := make(map[string]struct{}, len(item.DependsOn))
+		for _, key := range item.DependsOn {
+			if _, ok := byKey[key]; !ok {
+				return nil, errors.Wrapf(ErrInvalidWorkflow, "task %q depends on unknown key %q", item.Key, key)
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			waiting[item.Key]++
+			dependents[key] = append(dependents[key], item.Key)
+		}
+	}
+
+	var ready []string
+	for _, item := range items {
+		if waiting[item.Key] == 0 {
+			ready = append(ready, item.Key)
+		}
+	}
+
+	order := make([]dto.WorkflowTaskRequest, 0, len(items))
+	for len(ready) > 0 {
+		key := ready[0]
+		ready = ready[1:]
+
+		item := byKey[key]
+		item.DependsOn = dedupe(item.DependsOn)
+		order = append(order, item)
+
+		for _, dependent := range dependents[key] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(items) {
+		var cycle []string
+		for key, n := range waiting {
+			if n > 0 {
+				cycle = append(cycle, key)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.Wrapf(ErrDependencyCycle, "between tasks %s", strings.Join(cycle, ", "))
+	}
+
+	return order, nil
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
+
+// GetWorkflow returns the tasks of a workflow in the order they were created
+func (s *TaskService) GetWorkflow(id string) (*model.Workflow, error) {
+	tasks, err := s.repo.ListWorkflowTasks(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "list workflow tasks")
+	}
+	if len(tasks) == 0 {
+		return nil, ErrWorkflowNotFound
+	}
+
+	return &model.Workflow{ID: id, Tasks: tasks}, nil
+}
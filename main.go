@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -24,9 +25,41 @@ func main() {
 
 	log := logger.New(cfg.Logger.ToLoggerConfig())
 
-	repo := repository.NewTaskRepository()
-	service := service.NewTaskService(repo, log)
-	handler := handler.NewTaskHandler(service, log)
+	repo, err := newTaskRepository(cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to open task repository", zap.Error(err))
+	}
+
+	// Durable backends keep schedules next to the tasks
+	scheduleRepo, ok := repo.(repository.ScheduleRepositoryInterface)
+	if !ok {
+		scheduleRepo = repository.NewScheduleRepository()
+	}
+
+	// Durable backends keep webhooks and their deliveries next to the tasks
+	webhookRepo, ok := repo.(repository.WebhookRepositoryInterface)
+	if !ok {
+		webhookRepo = repository.NewWebhookRepository()
+	}
+
+	// The search index is rebuilt from the stored tasks on every start
+	indexedRepo, err := repository.NewIndexedTaskRepository(repo)
+	if err != nil {
+		log.Fatal("Failed to build task search index", zap.Error(err))
+	}
+
+	serviceConfig := cfg.Service.ToServiceConfig()
+	taskService := service.NewTaskService(indexedRepo, log, serviceConfig)
+	scheduleService := service.NewScheduleService(scheduleRepo, taskService, log, serviceConfig.Schedules)
+	// Without a secret of its own, deliveries could be forged, so webhooks stay off
+	webhookService, err := service.NewWebhookService(webhookRepo, taskService, log, serviceConfig.Webhooks)
+	if err != nil {
+		log.Warn("Webhooks are disabled", zap.Error(err), zap.String("env", config.WebhookSecretEnv))
+	}
+	autoscaler := service.NewAutoscaler(taskService, log, serviceConfig.Autoscaler)
+	taskHandler := handler.NewTaskHandler(taskService, log)
+	scheduleHandler := handler.NewScheduleHandler(scheduleService, log)
+	adminHandler := handler.NewAdminHandler(taskService, autoscaler, log)
 
 	router := gin.New()
 
@@ -45,7 +78,12 @@ func main() {
 		)
 	})
 
-	handler.RegisterRoutes(router)
+	taskHandler.RegisterRoutes(router)
+	scheduleHandler.RegisterRoutes(router)
+	if webhookService != nil {
+		handler.NewWebhookHandler(webhookService, log).RegisterRoutes(router)
+	}
+	adminHandler.RegisterRoutes(router)
 
 	srv := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
@@ -68,13 +106,48 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := service.Shutdown(ctx); err != nil {
+	// Stop schedules first so they do not create tasks for a stopped service
+	if err := scheduleService.Shutdown(ctx); err != nil {
+		log.Error("Error shutting down schedule service", err)
+	}
+
+	if err := autoscaler.Shutdown(ctx); err != nil {
+		log.Error("Error shutting down autoscaler", err)
+	}
+
+	if err := taskService.Shutdown(ctx); err != nil {
 		log.Error("Error shutting down task service", err)
 	}
 
+	if webhookService != nil {
+		if err := webhookService.Shutdown(ctx); err != nil {
+			log.Error("Error shutting down webhook service", err)
+		}
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Error shutting down server", err)
 	}
 
+	if closer, ok := repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Error("Error closing task repository", err)
+		}
+	}
+
 	log.Info("Server stopped")
 }
+
+// newTaskRepository creates the task repository for the configured storage backend
+func newTaskRepository(cfg config.StorageConfig) (repository.TaskRepositoryInterface, error) {
+	switch cfg.Backend {
+	case "", config.StorageBackendMemory:
+		return repository.NewTaskRepository(), nil
+	case config.StorageBackendFile:
+		return repository.NewFileTaskRepository(cfg.ToFileRepositoryConfig()) //nolint:wrapcheck
+	case config.StorageBackendSQLite:
+		return repository.NewSQLiteTaskRepository(cfg.ToSQLiteRepositoryConfig()) //nolint:wrapcheck
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}